@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/auth"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) authenticateUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+    // authenticate the token, then refuse suspended users
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok || !cfg.checkNotSuspended(w, r, userID) {
+        return uuid.Nil, false
+    }
+    return userID, true
+}
+
+func (cfg *apiConfig) authenticateToken(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+    // check the bearer token and respond with an error if it is invalid
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "missing access token", err)
+        return uuid.Nil, false
+    }
+    userID, err := cfg.validateAccessToken(r.Context(), token)
+    if errors.Is(err, errTokenRevoked) {
+        respondWithError(w, http.StatusUnauthorized, "access token revoked", err)
+        return uuid.Nil, false
+    } else if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "invalid JWT", err)
+        return uuid.Nil, false
+    }
+    return userID, true
+}
+
+var errTokenRevoked = errors.New("token issued before the user's credentials changed")
+
+// validate an access token, refusing those issued before the user last
+// changed their password or email
+func (cfg *apiConfig) validateAccessToken(ctx context.Context, token string) (uuid.UUID, error) {
+    userID, issuedAt, err := auth.ValidateJWTIssuedAt(token, cfg.secret)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    validAfter, err := cfg.dbQueries.GetUserTokensValidAfter(ctx, userID)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    if validAfter.Valid && issuedAt.Before(validAfter.Time) {
+        return uuid.Nil, errTokenRevoked
+    }
+    return userID, nil
+}
+
+func (cfg *apiConfig) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+    // only suspensions that are permanent or not yet expired are found
+    suspension, err := cfg.dbQueries.GetActiveSuspension(r.Context(), userID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return true
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking account status", err)
+        return false
+    }
+    msg := "account suspended"
+    if suspension.SuspendedUntil.Valid {
+        msg = fmt.Sprintf("account suspended until %s", suspension.SuspendedUntil.Time.Format(time.RFC3339))
+    }
+    respondWithError(w, http.StatusForbidden, msg, errors.New(suspension.SuspensionReason.String))
+    return false
+}
+
+// user roles, moderators and admins can act on reports
+const (
+    roleUser       = "user"
+    roleModerator  = "moderator"
+    roleAdmin      = "admin"
+)
+
+func (cfg *apiConfig) authenticateModerator(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+    // authenticate the user, then check their role
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return uuid.Nil, false
+    }
+    user, err := cfg.dbQueries.GetUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "user not found", err)
+        return uuid.Nil, false
+    }
+    if user.Role != roleModerator && user.Role != roleAdmin {
+        respondWithError(w, http.StatusForbidden, "moderator access required", nil)
+        return uuid.Nil, false
+    }
+    return userID, true
+}
+
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+    // anonymous viewers are allowed, so an invalid token is treated as no token
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        return uuid.NullUUID{}
+    }
+    userID, err := cfg.validateAccessToken(r.Context(), token)
+    if err != nil {
+        return uuid.NullUUID{}
+    }
+    return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+func (cfg *apiConfig) authenticateAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+    // authenticate the user, then check they are an admin
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return uuid.Nil, false
+    }
+    user, err := cfg.dbQueries.GetUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "user not found", err)
+        return uuid.Nil, false
+    }
+    if user.Role != roleAdmin {
+        respondWithError(w, http.StatusForbidden, "admin access required", nil)
+        return uuid.Nil, false
+    }
+    return userID, true
+}
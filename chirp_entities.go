@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// hashtags and mentions in a chirp body, with rune offsets [start, end)
+type ChirpEntities struct {
+    Hashtags  []HashtagEntity  `json:"hashtags"`
+    Mentions  []MentionEntity  `json:"mentions"`
+}
+
+type HashtagEntity struct {
+    Tag      string    `json:"tag"`
+    Indices  [2]int32  `json:"indices"`
+}
+
+type MentionEntity struct {
+    UserID   uuid.UUID  `json:"user_id"`
+    Indices  [2]int32   `json:"indices"`
+}
+
+// parse the hashtags and mentions out of a stored chirp body and record them,
+// skipping mentions that do not resolve to a user
+func (cfg *apiConfig) saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+    for _, tag := range chirptext.Hashtags(chirp.Body) {
+        err := q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+            ChirpID:    chirp.ID,
+            Tag:        strings.ToLower(tag.Text),
+            StartIndex: int32(tag.Start),
+            EndIndex:   int32(tag.End),
+        })
+        if err != nil {
+            return err
+        }
+    }
+
+    mentions := chirptext.Mentions(chirp.Body)
+    users, err := cfg.resolveMentions(ctx, q, mentions)
+    if err != nil {
+        return err
+    }
+    for _, mention := range mentions {
+        userID, ok := users[mention.Text]
+        if !ok {
+            continue
+        }
+        err := q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+            ChirpID:    chirp.ID,
+            UserID:     userID,
+            StartIndex: int32(mention.Start),
+            EndIndex:   int32(mention.End),
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// map the text of each mention to the user it refers to, by handle or by ID
+func (cfg *apiConfig) resolveMentions(ctx context.Context, q *database.Queries, mentions []chirptext.Entity) (map[string]uuid.UUID, error) {
+    users := map[string]uuid.UUID{}
+    ids := []uuid.UUID{}
+    handles := []string{}
+    for _, mention := range mentions {
+        if id, err := uuid.Parse(mention.Text); err == nil {
+            ids = append(ids, id)
+        } else {
+            handles = append(handles, strings.ToLower(mention.Text))
+        }
+    }
+    if len(ids) > 0 {
+        found, err := q.GetExistingUserIDs(ctx, ids)
+        if err != nil {
+            return nil, err
+        }
+        for _, id := range found {
+            users[id.String()] = id
+        }
+    }
+    if len(handles) > 0 {
+        found, err := q.GetUserIDsByHandles(ctx, handles)
+        if err != nil {
+            return nil, err
+        }
+        byHandle := make(map[string]uuid.UUID, len(found))
+        for _, user := range found {
+            byHandle[user.Handle] = user.ID
+        }
+        for _, mention := range mentions {
+            if id, ok := byHandle[strings.ToLower(mention.Text)]; ok {
+                users[mention.Text] = id
+            }
+        }
+    }
+    return users, nil
+}
+
+// attach the stored entities to recast chirps in two queries
+func (cfg *apiConfig) attachChirpEntities(ctx context.Context, items []Chirp, ids []uuid.UUID) error {
+    byID := make(map[uuid.UUID]*ChirpEntities, len(items))
+    for i := range items {
+        items[i].Entities = ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}}
+        byID[items[i].ID] = &items[i].Entities
+    }
+
+    hashtags, err := cfg.dbQueries.GetHashtagsForChirps(ctx, ids)
+    if err != nil {
+        return err
+    }
+    for _, tag := range hashtags {
+        e := byID[tag.ChirpID]
+        e.Hashtags = append(e.Hashtags, HashtagEntity{Tag: tag.Tag, Indices: [2]int32{tag.StartIndex, tag.EndIndex}})
+    }
+
+    mentions, err := cfg.dbQueries.GetMentionsForChirps(ctx, ids)
+    if err != nil {
+        return err
+    }
+    for _, mention := range mentions {
+        e := byID[mention.ChirpID]
+        e.Mentions = append(e.Mentions, MentionEntity{UserID: mention.UserID, Indices: [2]int32{mention.StartIndex, mention.EndIndex}})
+    }
+    return nil
+}
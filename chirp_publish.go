@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// problem with a chirp that is the author's to fix, reported as is
+type chirpError struct {
+    status  int
+    msg     string
+    err     error
+}
+
+func (e *chirpError) Error() string {
+    if e.err != nil {
+        return fmt.Sprintf("%s: %s", e.msg, e.err)
+    }
+    return e.msg
+}
+
+// validate and moderate a chirp request, resolving quotes of and replies to a
+// rechirp to the original. Returns the chirp to create and its distinct media
+// IDs, or a *chirpError if the request cannot be published.
+func (cfg *apiConfig) prepareChirp(ctx context.Context, userID uuid.UUID, chp *InitChirp) (database.CreateChirpParams, []uuid.UUID, error) {
+    // validate chirp length and moderate
+    maxLength, err := cfg.chirpLengthLimit(ctx, userID)
+    if err != nil {
+        return database.CreateChirpParams{}, nil, err
+    }
+    moderated, err := cfg.validateChirpBody(chp.Body, maxLength)
+    if err != nil {
+        return database.CreateChirpParams{}, nil, &chirpError{http.StatusBadRequest, "invalid chirp", err}
+    }
+
+    // media are given by the IDs returned from uploads
+    mediaIDs := []uuid.UUID{}
+    for _, mediaID := range chp.MediaIDs {
+        if !slices.Contains(mediaIDs, mediaID) {
+            mediaIDs = append(mediaIDs, mediaID)
+        }
+    }
+    if len(mediaIDs) > maxChirpMedia {
+        return database.CreateChirpParams{}, nil, &chirpError{http.StatusBadRequest, fmt.Sprintf("a chirp can have at most %d media", maxChirpMedia), nil}
+    }
+
+    // quotes of a rechirp quote the original instead
+    quoteOf := chp.QuoteOf
+    if quoteOf.Valid {
+        quoted, err := cfg.dbQueries.GetChirp(ctx, quoteOf.UUID)
+        if err == nil && quoted.RechirpOf.Valid {
+            quoteOf = quoted.RechirpOf
+            quoted, err = cfg.dbQueries.GetChirp(ctx, quoteOf.UUID)
+        }
+        if err != nil {
+            return database.CreateChirpParams{}, nil, &chirpError{http.StatusNotFound, "quoted chirp not found", err}
+        }
+        ok, err := cfg.canViewChirp(ctx, uuid.NullUUID{UUID: userID, Valid: true}, quoted)
+        if err != nil {
+            return database.CreateChirpParams{}, nil, err
+        }
+        if !ok {
+            return database.CreateChirpParams{}, nil, &chirpError{http.StatusNotFound, "quoted chirp not found", nil}
+        }
+    }
+
+    // replies to a rechirp answer the original, and continue its thread
+    replyTo := chp.ReplyTo
+    threadID := uuid.NullUUID{}
+    parentVisibility := ""
+    if replyTo.Valid {
+        parent, err := cfg.dbQueries.GetChirp(ctx, replyTo.UUID)
+        if err == nil && parent.RechirpOf.Valid {
+            replyTo = parent.RechirpOf
+            parent, err = cfg.dbQueries.GetChirp(ctx, replyTo.UUID)
+        }
+        if err != nil {
+            return database.CreateChirpParams{}, nil, &chirpError{http.StatusNotFound, "chirp replied to not found", err}
+        }
+        ok, err := cfg.canViewChirp(ctx, uuid.NullUUID{UUID: userID, Valid: true}, parent)
+        if err != nil {
+            return database.CreateChirpParams{}, nil, err
+        }
+        if !ok {
+            return database.CreateChirpParams{}, nil, &chirpError{http.StatusNotFound, "chirp replied to not found", nil}
+        }
+        parentVisibility = parent.Visibility
+        threadID = parent.ThreadID
+        if !threadID.Valid {
+            threadID = replyTo
+        }
+
+        // blocks with the parent's author hide the parent, and blocks with
+        // whoever started the thread keep the user out of the rest of it
+        if threadID != replyTo {
+            root, err := cfg.dbQueries.GetChirpIncludingDeleted(ctx, threadID.UUID)
+            if err != nil && !errors.Is(err, sql.ErrNoRows) {
+                return database.CreateChirpParams{}, nil, err
+            }
+            if err == nil {
+                blocked, err := cfg.isBlocked(ctx, userID, root.UserID)
+                if err != nil {
+                    return database.CreateChirpParams{}, nil, err
+                }
+                if blocked {
+                    return database.CreateChirpParams{}, nil, &chirpError{http.StatusForbidden, "cannot reply in this thread", nil}
+                }
+            }
+        }
+    }
+
+    if chp.Poll != nil {
+        if err = validatePoll(chp.Poll); err != nil {
+            return database.CreateChirpParams{}, nil, err
+        }
+    }
+
+    // chirps are public unless asked otherwise, and replies are seen no more
+    // widely than the chirp they answer
+    if chp.Visibility == "" {
+        chp.Visibility = visibilityPublic
+        if replyTo.Valid {
+            chp.Visibility = parentVisibility
+        }
+    }
+    if !validVisibility(chp.Visibility) {
+        return database.CreateChirpParams{}, nil, &chirpError{http.StatusBadRequest, fmt.Sprintf("unknown visibility '%s'", chp.Visibility), nil}
+    }
+    if replyTo.Valid && visibilityReach[chp.Visibility] > visibilityReach[parentVisibility] {
+        return database.CreateChirpParams{}, nil, &chirpError{http.StatusBadRequest, fmt.Sprintf("a reply to a %s chirp cannot be %s", parentVisibility, chp.Visibility), nil}
+    }
+
+    params := database.CreateChirpParams{
+        Body:            moderated.Body,
+        UserID:          userID,
+        QuoteOf:         quoteOf,
+        ModerationState: moderated.State(),
+        ModerationRules: moderated.Rules,
+        Visibility:      chp.Visibility,
+        ReplyTo:         replyTo,
+        ThreadID:        threadID,
+    }
+    return params, mediaIDs, nil
+}
+
+// create a prepared chirp within a transaction, counting the quote or reply on
+// the original, attaching its media, poll and entities and announcing it
+func (cfg *apiConfig) createChirp(ctx context.Context, qtx *database.Queries, params database.CreateChirpParams, mediaIDs []uuid.UUID, poll *InitPoll) (database.Chirp, error) {
+    chirp, err := qtx.CreateChirp(ctx, params)
+    if err != nil {
+        return database.Chirp{}, err
+    }
+    if params.QuoteOf.Valid {
+        err = qtx.IncrementChirpQuotes(ctx, params.QuoteOf.UUID)
+        if err != nil {
+            return database.Chirp{}, err
+        }
+    }
+    if params.ReplyTo.Valid {
+        err = qtx.IncrementChirpReplies(ctx, params.ReplyTo.UUID)
+        if err != nil {
+            return database.Chirp{}, err
+        }
+    }
+    if len(mediaIDs) > 0 {
+        attached, err := qtx.AttachMediaToChirp(ctx, database.AttachMediaToChirpParams{
+            ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+            Ids:     mediaIDs,
+            UserID:  params.UserID,
+        })
+        if err != nil {
+            return database.Chirp{}, err
+        }
+        if len(attached) != len(mediaIDs) {
+            return database.Chirp{}, &chirpError{http.StatusBadRequest, "media not found or already attached", nil}
+        }
+    }
+    if poll != nil {
+        err = createPoll(ctx, qtx, chirp.ID, poll)
+        if err != nil {
+            return database.Chirp{}, err
+        }
+    }
+    err = cfg.saveChirpEntities(ctx, qtx, chirp)
+    if err != nil {
+        return database.Chirp{}, err
+    }
+    if chirp.ModerationState == chirpStateVisible {
+        err = publishEvent(ctx, qtx, eventChirpCreated, chirpEventData(chirp))
+        if err != nil {
+            return database.Chirp{}, err
+        }
+    }
+    return chirp, nil
+}
+
+// respond with a chirp error, or a server error for anything else
+func respondWithChirpError(w http.ResponseWriter, msg string, err error) {
+    var cerr *chirpError
+    if errors.As(err, &cerr) {
+        respondWithError(w, cerr.status, cerr.msg, cerr.err)
+        return
+    }
+    respondWithError(w, http.StatusInternalServerError, msg, err)
+}
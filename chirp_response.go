@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// quoted chirp, or a placeholder with only its ID once the original is deleted
+type QuotedChirp struct {
+    *Chirp
+    ID           uuid.UUID  `json:"id"`
+    Unavailable  bool       `json:"unavailable,omitempty"`
+}
+
+// recast a database chirp to the JSON chirp, without any viewer state
+func chirpFromDB(chirp database.Chirp) Chirp {
+    item := Chirp{
+        ID:           chirp.ID,
+        CreatedAt:    chirp.CreatedAt,
+        UpdatedAt:    chirp.UpdatedAt,
+        Body:         chirp.Body,
+        UserID:       chirp.UserID,
+        LikeCount:    chirp.LikeCount,
+        RechirpCount: chirp.RechirpCount,
+        QuoteCount:   chirp.QuoteCount,
+        ReplyCount:   chirp.ReplyCount,
+        Edited:       chirp.EditedAt.Valid,
+        Visibility:   chirp.Visibility,
+    }
+    if chirp.ReplyTo.Valid {
+        item.ReplyTo = &chirp.ReplyTo.UUID
+    }
+    if chirp.ThreadID.Valid {
+        item.ThreadID = &chirp.ThreadID.UUID
+    }
+    if chirp.DeletedAt.Valid {
+        item.DeletedAt = &chirp.DeletedAt.Time
+    }
+    return item
+}
+
+// recast database chirps and fill in the viewer state and the embedded
+// originals of rechirps and quotes, with one query per field rather than one
+// per chirp
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) ([]Chirp, error) {
+    // leave out chirps the viewer is not allowed to see
+    chirps, err := cfg.filterViewable(ctx, viewer, chirps)
+    if err != nil {
+        return nil, err
+    }
+    items, err := cfg.recastChirps(ctx, viewer, chirps)
+    if err != nil {
+        return nil, err
+    }
+
+    // collect the originals referenced by rechirps and quotes
+    refIDs := []uuid.UUID{}
+    for _, chirp := range chirps {
+        if chirp.RechirpOf.Valid {
+            refIDs = append(refIDs, chirp.RechirpOf.UUID)
+        }
+        if chirp.QuoteOf.Valid {
+            refIDs = append(refIDs, chirp.QuoteOf.UUID)
+        }
+    }
+    if len(refIDs) == 0 {
+        return items, nil
+    }
+    originals, err := cfg.dbQueries.GetChirpsByIDs(ctx, refIDs)
+    if err != nil {
+        return nil, err
+    }
+    originals, err = cfg.filterViewable(ctx, viewer, originals)
+    if err != nil {
+        return nil, err
+    }
+    embedded, err := cfg.recastChirps(ctx, viewer, originals)
+    if err != nil {
+        return nil, err
+    }
+    byID := make(map[uuid.UUID]*Chirp, len(embedded))
+    for i := range embedded {
+        byID[embedded[i].ID] = &embedded[i]
+    }
+
+    // embed the originals, using a placeholder for unavailable quotes and
+    // dropping rechirps of chirps that can no longer be seen
+    shown := items[:0]
+    for i, chirp := range chirps {
+        if chirp.RechirpOf.Valid {
+            original, ok := byID[chirp.RechirpOf.UUID]
+            if !ok {
+                continue
+            }
+            items[i].RechirpOf = original
+        }
+        if chirp.QuoteOf.Valid {
+            original, ok := byID[chirp.QuoteOf.UUID]
+            items[i].QuoteOf = &QuotedChirp{Chirp: original, ID: chirp.QuoteOf.UUID, Unavailable: !ok}
+        }
+        shown = append(shown, items[i])
+    }
+    return shown, nil
+}
+
+// recast database chirps with their entities, and mark the ones the viewer
+// has interacted with
+func (cfg *apiConfig) recastChirps(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) ([]Chirp, error) {
+    items := make([]Chirp, 0, len(chirps))
+    ids := make([]uuid.UUID, 0, len(chirps))
+    for _, chirp := range chirps {
+        items = append(items, chirpFromDB(chirp))
+        ids = append(ids, chirp.ID)
+    }
+    if len(items) == 0 {
+        return items, nil
+    }
+
+    // attach authors, hashtags, mentions, media and polls
+    err := cfg.attachChirpAuthors(ctx, items)
+    if err != nil {
+        return nil, err
+    }
+    err = cfg.attachChirpEntities(ctx, items, ids)
+    if err != nil {
+        return nil, err
+    }
+    err = cfg.attachChirpMedia(ctx, items, ids)
+    if err != nil {
+        return nil, err
+    }
+    err = cfg.attachChirpPolls(ctx, viewer, items, ids)
+    if err != nil {
+        return nil, err
+    }
+    if !viewer.Valid {
+        return items, nil
+    }
+
+    // show authors how their own chirps were moderated
+    for i, chirp := range chirps {
+        if chirp.UserID == viewer.UUID && (chirp.ModerationState != chirpStateVisible || len(chirp.ModerationRules) > 0) {
+            items[i].Moderation = &ChirpModeration{State: chirp.ModerationState, Rules: chirp.ModerationRules}
+        }
+    }
+
+    // mark chirps liked by the viewer
+    liked, err := cfg.dbQueries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+        UserID:   viewer.UUID,
+        ChirpIds: ids,
+    })
+    if err != nil {
+        return nil, err
+    }
+    likedSet := make(map[uuid.UUID]struct{}, len(liked))
+    for _, id := range liked {
+        likedSet[id] = struct{}{}
+    }
+    for i := range items {
+        _, items[i].LikedByMe = likedSet[items[i].ID]
+    }
+
+    // mark chirps the viewer bookmarked, which no one else ever sees
+    bookmarked, err := cfg.dbQueries.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{
+        UserID:   viewer.UUID,
+        ChirpIds: ids,
+    })
+    if err != nil {
+        return nil, err
+    }
+    bookmarkedSet := make(map[uuid.UUID]struct{}, len(bookmarked))
+    for _, id := range bookmarked {
+        bookmarkedSet[id] = struct{}{}
+    }
+    for i := range items {
+        _, items[i].BookmarkedByMe = bookmarkedSet[items[i].ID]
+    }
+
+    return items, nil
+}
+
+// hydrate chirps for a list, leaving out authors the viewer has blocked or
+// muted and anyone who has blocked them, along with rechirps of their chirps
+func (cfg *apiConfig) listChirps(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) ([]Chirp, error) {
+    if !viewer.Valid {
+        return cfg.hydrateChirps(ctx, viewer, chirps)
+    }
+    hiddenIDs, err := cfg.dbQueries.GetHiddenUserIDs(ctx, viewer.UUID)
+    if err != nil {
+        return nil, err
+    }
+    if len(hiddenIDs) == 0 {
+        return cfg.hydrateChirps(ctx, viewer, chirps)
+    }
+    hidden := make(map[uuid.UUID]struct{}, len(hiddenIDs))
+    for _, id := range hiddenIDs {
+        hidden[id] = struct{}{}
+    }
+
+    // drop their own chirps before hydrating, then anything embedding them
+    kept := make([]database.Chirp, 0, len(chirps))
+    for _, chirp := range chirps {
+        if _, ok := hidden[chirp.UserID]; !ok {
+            kept = append(kept, chirp)
+        }
+    }
+    items, err := cfg.hydrateChirps(ctx, viewer, kept)
+    if err != nil {
+        return nil, err
+    }
+    shown := items[:0]
+    for _, item := range items {
+        if item.RechirpOf != nil {
+            if _, ok := hidden[item.RechirpOf.UserID]; ok {
+                continue
+            }
+        }
+        if item.QuoteOf != nil && item.QuoteOf.Chirp != nil {
+            if _, ok := hidden[item.QuoteOf.UserID]; ok {
+                item.QuoteOf = &QuotedChirp{ID: item.QuoteOf.ID, Unavailable: true}
+            }
+        }
+        shown = append(shown, item)
+    }
+    return shown, nil
+}
+
+// a chirp that hydrating left out, like a rechirp whose original the viewer
+// can no longer see
+var errChirpUnavailable = errors.New("chirp is unavailable")
+
+// hydrate a single chirp for the viewer
+func (cfg *apiConfig) hydrateChirp(ctx context.Context, viewer uuid.NullUUID, chirp database.Chirp) (Chirp, error) {
+    items, err := cfg.hydrateChirps(ctx, viewer, []database.Chirp{chirp})
+    if err != nil {
+        return Chirp{}, err
+    }
+    if len(items) == 0 {
+        return Chirp{}, errChirpUnavailable
+    }
+    return items[0], nil
+}
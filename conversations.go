@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+    // largest group, including whoever started it
+    maxConversationParticipants  = 10
+    maxMessageLength             = 1000
+)
+
+type Conversation struct {
+    ID            uuid.UUID                  `json:"id"`
+    CreatedAt     time.Time                  `json:"created_at"`
+    UpdatedAt     time.Time                  `json:"updated_at"`
+    IsGroup       bool                       `json:"is_group"`
+    Muted         bool                       `json:"muted"`
+    UnreadCount   int64                      `json:"unread_count"`
+    Participants  []ConversationParticipant  `json:"participants"`
+    LastMessage   *Message                   `json:"last_message,omitempty"`
+}
+
+// participant with their read receipt, the last message they have read
+type ConversationParticipant struct {
+    User               AuthorSummary  `json:"user"`
+    JoinedAt           time.Time      `json:"joined_at"`
+    Left               bool           `json:"left"`
+    LastReadMessageID  uuid.NullUUID  `json:"last_read_message_id"`
+    LastReadAt         *time.Time     `json:"last_read_at,omitempty"`
+}
+
+// message in a conversation. The sender is null once their account is gone.
+type Message struct {
+    ID              uuid.UUID      `json:"id"`
+    CreatedAt       time.Time      `json:"created_at"`
+    ConversationID  uuid.UUID      `json:"conversation_id"`
+    SenderID        uuid.NullUUID  `json:"sender_id"`
+    Body            string         `json:"body"`
+}
+
+func messageFromDB(message database.Message) Message {
+    return Message{
+        ID:             message.ID,
+        CreatedAt:      message.CreatedAt,
+        ConversationID: message.ConversationID,
+        SenderID:       message.SenderID,
+        Body:           message.Body,
+    }
+}
+
+// key of the one-to-one conversation between two users, the same whichever
+// of them starts it
+func directKey(a, b uuid.UUID) string {
+    ids := []string{a.String(), b.String()}
+    slices.Sort(ids)
+    return ids[0] + ":" + ids[1]
+}
+
+// find the user's place in a conversation they have not left, responding
+// with not found otherwise so conversations are not revealed to outsiders
+func (cfg *apiConfig) activeParticipant(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.ConversationParticipant, bool) {
+    conversationID, ok := parsePathUUID(w, r, "conversation_id")
+    if !ok {
+        return database.ConversationParticipant{}, false
+    }
+    participant, err := cfg.dbQueries.GetConversationParticipant(r.Context(), database.GetConversationParticipantParams{
+        ConversationID: conversationID,
+        UserID:         userID,
+    })
+    if errors.Is(err, sql.ErrNoRows) || (err == nil && participant.LeftAt.Valid) {
+        respondWithError(w, http.StatusNotFound, "conversation not found", nil)
+        return database.ConversationParticipant{}, false
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation", err)
+        return database.ConversationParticipant{}, false
+    }
+    return participant, true
+}
+
+// recast conversations for one of their participants, with the participants,
+// latest messages and unread counts loaded in one batch each
+func (cfg *apiConfig) recastConversations(ctx context.Context, userID uuid.UUID, conversations []database.Conversation) ([]Conversation, error) {
+    items := make([]Conversation, 0, len(conversations))
+    ids := make([]uuid.UUID, 0, len(conversations))
+    for _, conversation := range conversations {
+        items = append(items, Conversation{
+            ID:           conversation.ID,
+            CreatedAt:    conversation.CreatedAt,
+            UpdatedAt:    conversation.UpdatedAt,
+            IsGroup:      conversation.IsGroup,
+            Participants: []ConversationParticipant{},
+        })
+        ids = append(ids, conversation.ID)
+    }
+    if len(ids) == 0 {
+        return items, nil
+    }
+
+    participants, err := cfg.dbQueries.GetConversationParticipants(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+    userIDs := []uuid.UUID{}
+    for _, participant := range participants {
+        userIDs = append(userIDs, participant.UserID)
+    }
+    users, err := cfg.dbQueries.GetUsersByIDs(ctx, userIDs)
+    if err != nil {
+        return nil, err
+    }
+    authors := make(map[uuid.UUID]AuthorSummary, len(users))
+    for _, user := range users {
+        authors[user.ID] = authorFromDB(user)
+    }
+    lastMessages, err := cfg.dbQueries.GetLastMessages(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+    unread, err := cfg.dbQueries.CountUnreadMessages(ctx, database.CountUnreadMessagesParams{
+        UserID:          userID,
+        ConversationIds: ids,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    byID := make(map[uuid.UUID]*Conversation, len(items))
+    for i := range items {
+        byID[items[i].ID] = &items[i]
+    }
+    for _, participant := range participants {
+        item := byID[participant.ConversationID]
+        if participant.UserID == userID {
+            item.Muted = participant.Muted
+        }
+        recast := ConversationParticipant{
+            User:              authors[participant.UserID],
+            JoinedAt:          participant.JoinedAt,
+            Left:              participant.LeftAt.Valid,
+            LastReadMessageID: participant.LastReadMessageID,
+        }
+        if participant.LastReadAt.Valid {
+            recast.LastReadAt = &participant.LastReadAt.Time
+        }
+        item.Participants = append(item.Participants, recast)
+    }
+    for _, message := range lastMessages {
+        last := messageFromDB(message)
+        byID[message.ConversationID].LastMessage = &last
+    }
+    for _, count := range unread {
+        byID[count.ConversationID].UnreadCount = count.Unread
+    }
+    return items, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/url"
+	"os"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/auth"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+    digestInterval       = time.Hour
+    digestBatch          = 50
+    digestNotifications  = 10
+    digestTopChirps      = 5
+)
+
+// how often users get a digest
+const (
+    digestOff     = "off"
+    digestDaily   = "daily"
+    digestWeekly  = "weekly"
+)
+
+var digestPeriods = map[string]time.Duration{
+    digestDaily:  24 * time.Hour,
+    digestWeekly: 7 * 24 * time.Hour,
+}
+
+//go:embed templates/digest.txt templates/digest.html templates/unsubscribe.html
+var digestTemplates embed.FS
+
+var (
+    digestText       = texttemplate.Must(texttemplate.ParseFS(digestTemplates, "templates/digest.txt"))
+    digestHTML       = htmltemplate.Must(htmltemplate.ParseFS(digestTemplates, "templates/digest.html"))
+    unsubscribePage  = htmltemplate.Must(htmltemplate.ParseFS(digestTemplates, "templates/unsubscribe.html"))
+)
+
+// what the digest templates are filled in with
+type digestData struct {
+    Handle          string
+    Period          string
+    UnreadCount     int64
+    Notifications   []Notification
+    Chirps          []Chirp
+    UnsubscribeURL  string
+}
+
+func newMailer() (mail.Mailer, error) {
+    switch mailer := os.Getenv("MAILER"); mailer {
+    case "", "file":
+        dir := os.Getenv("MAIL_DIR")
+        if dir == "" {
+            dir = "mail"
+        }
+        return mail.NewFileMailer(dir)
+    case "smtp":
+        return mail.NewSMTPMailer(mail.SMTPConfig{
+            Addr:     os.Getenv("SMTP_ADDR"),
+            Username: os.Getenv("SMTP_USERNAME"),
+            Password: os.Getenv("SMTP_PASSWORD"),
+        })
+    default:
+        return nil, fmt.Errorf("unknown mailer '%s'", mailer)
+    }
+}
+
+func (cfg *apiConfig) runDigests(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cfg.sendDueDigests(ctx)
+        }
+    }
+}
+
+// send the digests that are due, a batch at a time. Users are claimed before
+// their digest is sent, so a failed send is skipped until the next period
+// rather than retried and possibly sent twice.
+func (cfg *apiConfig) sendDueDigests(ctx context.Context) {
+    for {
+        users, err := cfg.dbQueries.ClaimDueDigests(ctx, digestBatch)
+        if err != nil {
+            log.Printf("error claiming digests: %s", err)
+            return
+        }
+        for _, user := range users {
+            if err = cfg.sendDigest(ctx, user); err != nil {
+                log.Printf("error sending digest to %s: %s", user.ID, err)
+            }
+        }
+        if len(users) < digestBatch {
+            return
+        }
+    }
+}
+
+// send a user their digest, unless nothing happened in the period
+func (cfg *apiConfig) sendDigest(ctx context.Context, user database.User) error {
+    data := digestData{
+        Handle:         user.Handle,
+        Period:         user.DigestFrequency,
+        UnsubscribeURL: cfg.unsubscribeURL(user.ID),
+    }
+    var err error
+    data.UnreadCount, err = cfg.dbQueries.CountUnreadNotifications(ctx, user.ID)
+    if err != nil {
+        return err
+    }
+    if data.UnreadCount > 0 {
+        notifications, err := cfg.dbQueries.GetUnreadNotifications(ctx, database.GetUnreadNotificationsParams{
+            UserID: user.ID,
+            Limit:  digestNotifications,
+        })
+        if err != nil {
+            return err
+        }
+        data.Notifications, err = cfg.recastNotifications(ctx, user.ID, notifications)
+        if err != nil {
+            return err
+        }
+    }
+    chirps, err := cfg.dbQueries.GetTopFollowedChirps(ctx, database.GetTopFollowedChirpsParams{
+        UserID:   user.ID,
+        Since:    time.Now().Add(-digestPeriods[user.DigestFrequency]),
+        PageSize: digestTopChirps,
+    })
+    if err != nil {
+        return err
+    }
+    data.Chirps, err = cfg.listChirps(ctx, uuid.NullUUID{UUID: user.ID, Valid: true}, chirps)
+    if err != nil {
+        return err
+    }
+    if data.UnreadCount == 0 && len(data.Chirps) == 0 {
+        return nil
+    }
+
+    text := &bytes.Buffer{}
+    if err = digestText.Execute(text, data); err != nil {
+        return err
+    }
+    html := &bytes.Buffer{}
+    if err = digestHTML.Execute(html, data); err != nil {
+        return err
+    }
+    return cfg.mailer.Send(ctx, mail.Message{
+        From:    cfg.mailFrom,
+        To:      user.Email,
+        Subject: fmt.Sprintf("Your %s Chirpy digest", user.DigestFrequency),
+        Text:    text.String(),
+        HTML:    html.String(),
+        // mail clients can unsubscribe with one click through a POST to the
+        // same link
+        Headers: map[string]string{
+            "List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+            "List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+        },
+    })
+}
+
+// link that turns off a user's digests without logging in
+func (cfg *apiConfig) unsubscribeURL(userID uuid.UUID) string {
+    query := url.Values{}
+    query.Set("user_id", userID.String())
+    query.Set("token", auth.MakeUnsubscribeToken(userID, cfg.secret))
+    return cfg.baseURL + "/api/notifications/digest/unsubscribe?" + query.Encode()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// long lived connections, which are drained on shutdown since the server
+// neither ends streaming responses nor waits for hijacked connections itself
+type connTracker struct {
+    mu        sync.Mutex
+    closed    bool
+    draining  chan struct{}
+    wg        sync.WaitGroup
+}
+
+func newConnTracker() *connTracker {
+    return &connTracker{draining: make(chan struct{})}
+}
+
+// track a new connection, unless the server is already draining
+func (t *connTracker) add() bool {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.closed {
+        return false
+    }
+    t.wg.Add(1)
+    return true
+}
+
+func (t *connTracker) done() {
+    t.wg.Done()
+}
+
+// ask every connection to finish up
+func (t *connTracker) drain() {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if !t.closed {
+        t.closed = true
+        close(t.draining)
+    }
+}
+
+// wait for the connections to finish, or for the context to be done
+func (t *connTracker) wait(ctx context.Context) error {
+    finished := make(chan struct{})
+    go func() {
+        t.wg.Wait()
+        close(finished)
+    }()
+    select {
+    case <-finished:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/events"
+	"github.com/google/uuid"
+)
+
+// Postgres channel every instance listens on
+const eventChannel = "chirpy_events"
+
+// types of event shared between instances
+const (
+    eventChirpCreated    = "chirp.created"
+    eventChirpDeleted    = "chirp.deleted"
+    eventNotification    = "notification"
+    eventMessageCreated  = "message.created"
+)
+
+// what an event is about. Subscribers look the rest up themselves, since
+// what they can see depends on who they are.
+type eventData struct {
+    ChirpID           uuid.UUID      `json:"chirp_id"`
+    MessageID         uuid.UUID      `json:"message_id"`
+    // author of the chirp or message, or recipient of the notification
+    UserID            uuid.UUID      `json:"user_id"`
+    QuoteOf           uuid.NullUUID  `json:"quote_of"`
+    ReplyTo           uuid.NullUUID  `json:"reply_to"`
+    ThreadID          uuid.NullUUID  `json:"thread_id"`
+    Visibility        string         `json:"visibility,omitempty"`
+    ModerationState   string         `json:"moderation_state,omitempty"`
+    Hashtags          []string       `json:"hashtags,omitempty"`
+    NotificationType  string         `json:"notification_type,omitempty"`
+    // who a private event is for
+    RecipientIDs      []uuid.UUID    `json:"recipient_ids,omitempty"`
+}
+
+// event data for a chirp, with what subscribers filter on
+func chirpEventData(chirp database.Chirp) eventData {
+    data := eventData{
+        ChirpID:         chirp.ID,
+        UserID:          chirp.UserID,
+        QuoteOf:         chirp.QuoteOf,
+        ReplyTo:         chirp.ReplyTo,
+        ThreadID:        chirp.ThreadID,
+        Visibility:      chirp.Visibility,
+        ModerationState: chirp.ModerationState,
+    }
+    for _, tag := range chirptext.Hashtags(chirp.Body) {
+        data.Hashtags = append(data.Hashtags, strings.ToLower(tag.Text))
+    }
+    return data
+}
+
+// publish an event to every instance and queue it for webhooks. Within a
+// transaction it is only delivered once the transaction commits, and dropped
+// if it rolls back.
+func publishEvent(ctx context.Context, q *database.Queries, kind string, data eventData) error {
+    dat, err := json.Marshal(data)
+    if err != nil {
+        return err
+    }
+    event := events.Event{ID: uuid.NewString(), Type: kind, Data: dat}
+    payload, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+    err = q.PublishEvent(ctx, database.PublishEventParams{
+        Channel: eventChannel,
+        Payload: string(payload),
+    })
+    if err != nil {
+        return err
+    }
+    return enqueueWebhooks(ctx, q, event, data)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/auth"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// fields that can be patched
+var accountFields = []string{"email", "password", "current_password", "handle", "display_name", "bio", "avatar_media_id"}
+
+// validation failure listing the problem with each field
+type FieldErrors struct {
+    Error   string             `json:"error"`
+    Fields  map[string]string  `json:"fields"`
+}
+
+// account after a patch, with a fresh access token when the email or password
+// changed, and a fresh refresh token when the password changed and every other
+// session was signed out
+type PatchedUser struct {
+    User
+    Token         string  `json:"token,omitempty"`
+    RefreshToken  string  `json:"refresh_token,omitempty"`
+}
+
+// PATCH /api/users/me takes a JSON merge patch: fields left out are kept, and
+// null clears the optional profile fields. Changing the email or password
+// needs the current password.
+func (cfg *apiConfig) handlerPatchUser(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    if ct := r.Header.Get("Content-Type"); ct != "" {
+        mediaType, _, err := mime.ParseMediaType(ct)
+        if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+            respondWithError(w, http.StatusUnsupportedMediaType, "expected application/merge-patch+json", err)
+            return
+        }
+    }
+    decoder := json.NewDecoder(r.Body)
+    patch := map[string]json.RawMessage{}
+    err := decoder.Decode(&patch)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    user, err := cfg.dbQueries.GetUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return
+    }
+
+    // apply the patch to the current account, collecting every field error
+    fields := map[string]string{}
+    for name := range patch {
+        if !slices.Contains(accountFields, name) {
+            fields[name] = "unknown field"
+        }
+    }
+    email := user.Email
+    if raw, ok := patch["email"]; ok {
+        if value, msg := patchString(raw, false); msg != "" {
+            fields["email"] = msg
+        } else if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+            fields["email"] = "not a valid email address"
+        } else {
+            email = value
+        }
+    }
+    password := ""
+    if raw, ok := patch["password"]; ok {
+        if value, msg := patchString(raw, false); msg != "" {
+            fields["password"] = msg
+        } else if value == "" {
+            fields["password"] = "cannot be empty"
+        } else {
+            password = value
+        }
+    }
+    handle := user.Handle
+    if raw, ok := patch["handle"]; ok {
+        if value, msg := patchString(raw, false); msg != "" {
+            fields["handle"] = msg
+        } else if err := validateHandle(value); err != nil {
+            fields["handle"] = err.Error()
+        } else {
+            handle = value
+        }
+    }
+    displayName := user.DisplayName
+    if raw, ok := patch["display_name"]; ok {
+        if value, msg := patchString(raw, true); msg != "" {
+            fields["display_name"] = msg
+        } else if displayName, err = normalizeProfileText("display name", value, maxDisplayNameLength); err != nil {
+            fields["display_name"] = err.Error()
+        }
+    }
+    bio := user.Bio
+    if raw, ok := patch["bio"]; ok {
+        if value, msg := patchString(raw, true); msg != "" {
+            fields["bio"] = msg
+        } else if bio, err = normalizeProfileText("bio", value, maxBioLength); err != nil {
+            fields["bio"] = err.Error()
+        }
+    }
+    avatarMediaID := user.AvatarMediaID
+    if raw, ok := patch["avatar_media_id"]; ok {
+        avatarMediaID = uuid.NullUUID{}
+        if err := json.Unmarshal(raw, &avatarMediaID); err != nil {
+            fields["avatar_media_id"] = "must be a UUID or null"
+        }
+    }
+
+    // sensitive changes need the current password
+    changingEmail := email != user.Email
+    changingPassword := password != "" && auth.CheckPasswordHash(password, user.HashedPassword) != nil
+    if changingEmail || changingPassword {
+        current := ""
+        if raw, ok := patch["current_password"]; ok {
+            current, _ = patchString(raw, false)
+        }
+        if current == "" {
+            fields["current_password"] = "required to change email or password"
+        } else if len(fields) == 0 && auth.CheckPasswordHash(current, user.HashedPassword) != nil {
+            respondWithError(w, http.StatusForbidden, "current password incorrect", nil)
+            return
+        }
+    }
+    if len(fields) > 0 {
+        respondWithJSON(w, http.StatusUnprocessableEntity, FieldErrors{Error: "invalid fields", Fields: fields})
+        return
+    }
+    if avatarMediaID != user.AvatarMediaID && !cfg.checkAvatar(w, r, userID, avatarMediaID) {
+        return
+    }
+
+    // only rehash a password that actually changed
+    hash := user.HashedPassword
+    if changingPassword {
+        hash, err = auth.HashPassword(password)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error hashing password", err)
+            return
+        }
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    _, err = qtx.UpdateUser(r.Context(), database.UpdateUserParams{
+        ID:             userID,
+        Email:          email,
+        HashedPassword: hash,
+    })
+    if err == nil {
+        user, err = qtx.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+            ID:            userID,
+            Handle:        handle,
+            DisplayName:   displayName,
+            Bio:           bio,
+            AvatarMediaID: avatarMediaID,
+        })
+    }
+    if isUniqueViolation(err) {
+        field := "email"
+        if isUniqueViolation(err, "users_handle") {
+            field = "handle"
+        }
+        respondWithJSON(w, http.StatusConflict, FieldErrors{Error: "already taken", Fields: map[string]string{field: "already taken"}})
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating user", err)
+        return
+    }
+
+    // new credentials refuse the access tokens issued before them, and a new
+    // password signs out every other session. This one is given fresh tokens.
+    item := PatchedUser{User: userFromDB(user)}
+    if changingEmail || changingPassword {
+        err = qtx.RevokeUserAccessTokens(r.Context(), userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error revoking access tokens", err)
+            return
+        }
+        item.Token, err = auth.MakeJWT(userID, cfg.secret, time.Hour)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error making JWT token", err)
+            return
+        }
+    }
+    if changingPassword {
+        err = qtx.RevokeUserRefreshTokens(r.Context(), userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error revoking sessions", err)
+            return
+        }
+        refreshToken, err := createRefreshToken(r.Context(), qtx, userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error creating refresh token", err)
+            return
+        }
+        item.RefreshToken = refreshToken.Token
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+// read a patched string, where null clears the field if it is optional
+func patchString(raw json.RawMessage, optional bool) (string, string) {
+    if string(raw) == "null" {
+        if optional {
+            return "", ""
+        }
+        return "", "cannot be removed"
+    }
+    value := ""
+    if err := json.Unmarshal(raw, &value); err != nil {
+        return "", "must be a string"
+    }
+    return value, ""
+}
+
+// new refresh token for the user, valid for 60 days
+func createRefreshToken(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.RefreshToken, error) {
+    token, err := auth.MakeRefreshToken()
+    if err != nil {
+        return database.RefreshToken{}, fmt.Errorf("error making refresh token: %w", err)
+    }
+    return q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+        Token:     token,
+        UserID:    userID,
+        ExpiresAt: time.Now().AddDate(0, 0, 60),
+    })
+}
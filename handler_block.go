@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// blocked or muted user, as listed for the user who blocked or muted them
+type UserRelation struct {
+    UserID     uuid.UUID  `json:"user_id"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// whether either user has blocked the other, in which case they cannot
+// interact with each other's chirps
+func (cfg *apiConfig) isBlocked(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+    if userA == userB {
+        return false, nil
+    }
+    return cfg.dbQueries.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{UserA: userA, UserB: userB})
+}
+
+// authenticate the user and find the target user in the path, who cannot be
+// the user themselves
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return uuid.Nil, uuid.Nil, false
+    }
+    targetID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return uuid.Nil, uuid.Nil, false
+    }
+    if targetID == userID {
+        respondWithError(w, http.StatusBadRequest, "cannot target yourself", nil)
+        return uuid.Nil, uuid.Nil, false
+    }
+    _, err := cfg.dbQueries.GetUser(r.Context(), targetID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return uuid.Nil, uuid.Nil, false
+    }
+    return userID, targetID, true
+}
+
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+
+    // blocking also drops any follows between the two users
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    _, err = qtx.BlockUser(r.Context(), database.BlockUserParams{BlockerID: userID, BlockedID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error blocking user", err)
+        return
+    }
+    err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{UserA: userID, UserB: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error removing follows", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing block", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.UnblockUser(r.Context(), database.UnblockUserParams{BlockerID: userID, BlockedID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error unblocking user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.MuteUser(r.Context(), database.MuteUserParams{MuterID: userID, MutedID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error muting user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.UnmuteUser(r.Context(), database.UnmuteUserParams{MuterID: userID, MutedID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error unmuting user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    blocks, err := cfg.dbQueries.GetBlocksByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting blocked users", err)
+        return
+    }
+    items := make([]UserRelation, 0, len(blocks))
+    for _, block := range blocks {
+        items = append(items, UserRelation{UserID: block.BlockedID, CreatedAt: block.CreatedAt})
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    mutes, err := cfg.dbQueries.GetMutesByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting muted users", err)
+        return
+    }
+    items := make([]UserRelation, 0, len(mutes))
+    for _, mute := range mutes {
+        items = append(items, UserRelation{UserID: mute.MutedID, CreatedAt: mute.CreatedAt})
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// longest bookmark collection name
+const maxCollectionNameLength = 50
+
+// bookmark request, optionally filing the chirp in a collection
+type InitBookmark struct {
+    CollectionID  uuid.NullUUID  `json:"collection_id"`
+}
+
+type InitCollection struct {
+    Name  string  `json:"name"`
+}
+
+type BookmarkCollection struct {
+    ID         uuid.UUID  `json:"id"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at"`
+    Name       string     `json:"name"`
+}
+
+// bookmarked chirp, as listed for the user who saved it
+type Bookmark struct {
+    Chirp         Chirp       `json:"chirp"`
+    CollectionID  *uuid.UUID  `json:"collection_id"`
+    CreatedAt     time.Time   `json:"created_at"`
+}
+
+type BookmarkPage struct {
+    Bookmarks   []Bookmark  `json:"bookmarks"`
+    NextCursor  string      `json:"next_cursor,omitempty"`
+}
+
+func collectionFromDB(collection database.BookmarkCollection) BookmarkCollection {
+    return BookmarkCollection{
+        ID:        collection.ID,
+        CreatedAt: collection.CreatedAt,
+        UpdatedAt: collection.UpdatedAt,
+        Name:      collection.Name,
+    }
+}
+
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    bm := InitBookmark{}
+    err := decoder.Decode(&bm)
+    if err != nil && !errors.Is(err, io.EOF) {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+
+    // bookmarking a rechirp saves the original
+    viewer := uuid.NullUUID{UUID: userID, Valid: true}
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err == nil && chirp.RechirpOf.Valid {
+        chirpID = chirp.RechirpOf.UUID
+        chirp, err = cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    }
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    ok, err = cfg.canViewChirp(r.Context(), viewer, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // the collection must be one of the user's own
+    if bm.CollectionID.Valid {
+        _, err = cfg.dbQueries.GetBookmarkCollection(r.Context(), database.GetBookmarkCollectionParams{
+            ID:     bm.CollectionID.UUID,
+            UserID: userID,
+        })
+        if err != nil {
+            respondWithError(w, http.StatusNotFound, "collection not found", err)
+            return
+        }
+    }
+    _, err = cfg.dbQueries.BookmarkChirp(r.Context(), database.BookmarkChirpParams{
+        UserID:       userID,
+        ChirpID:      chirpID,
+        CollectionID: bm.CollectionID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error bookmarking chirp", err)
+        return
+    }
+
+    item, err := cfg.hydrateChirp(r.Context(), viewer, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerUnbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err == nil && chirp.RechirpOf.Valid {
+        chirpID = chirp.RechirpOf.UUID
+    }
+    n, err := cfg.dbQueries.UnbookmarkChirp(r.Context(), database.UnbookmarkChirpParams{
+        UserID:  userID,
+        ChirpID: chirpID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error removing bookmark", err)
+        return
+    }
+    if n == 0 {
+        respondWithError(w, http.StatusNotFound, "bookmark not found", nil)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerGetBookmarks(w http.ResponseWriter, r *http.Request) {
+    // bookmarks are only ever listed for the user who saved them
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    collectionID := uuid.NullUUID{}
+    if value := r.URL.Query().Get("collection_id"); value != "" {
+        id, err := uuid.Parse(value)
+        if err != nil {
+            respondWithError(w, http.StatusBadRequest, "error parsing UUID from collection_id", err)
+            return
+        }
+        collectionID = uuid.NullUUID{UUID: id, Valid: true}
+    }
+
+    bookmarks, err := cfg.dbQueries.GetBookmarks(r.Context(), database.GetBookmarksParams{
+        UserID:          userID,
+        CollectionID:    collectionID,
+        BeforeCreatedAt: cursor.createdAt,
+        BeforeChirpID:   cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting bookmarks", err)
+        return
+    }
+
+    // load the chirps, leaving out any the user can no longer see
+    ids := make([]uuid.UUID, 0, len(bookmarks))
+    for _, bookmark := range bookmarks {
+        ids = append(ids, bookmark.ChirpID)
+    }
+    chirps, err := cfg.dbQueries.GetChirpsByIDs(r.Context(), ids)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    items, err := cfg.listChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    byID := make(map[uuid.UUID]Chirp, len(items))
+    for _, item := range items {
+        byID[item.ID] = item
+    }
+
+    page := BookmarkPage{Bookmarks: []Bookmark{}}
+    for _, bookmark := range bookmarks {
+        item, ok := byID[bookmark.ChirpID]
+        if !ok {
+            continue
+        }
+        entry := Bookmark{Chirp: item, CreatedAt: bookmark.CreatedAt}
+        if bookmark.CollectionID.Valid {
+            entry.CollectionID = &bookmark.CollectionID.UUID
+        }
+        page.Bookmarks = append(page.Bookmarks, entry)
+    }
+    if len(bookmarks) == int(limit) {
+        last := bookmarks[len(bookmarks)-1]
+        page.NextCursor = encodeCursor(last.CreatedAt, last.ChirpID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
+
+// decode and check a collection request
+func decodeCollection(w http.ResponseWriter, r *http.Request) (string, bool) {
+    decoder := json.NewDecoder(r.Body)
+    coll := InitCollection{}
+    err := decoder.Decode(&coll)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return "", false
+    }
+    name := strings.TrimSpace(coll.Name)
+    if name == "" || utf8.RuneCountInString(name) > maxCollectionNameLength {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("collection names must be 1 to %d characters", maxCollectionNameLength), nil)
+        return "", false
+    }
+    return name, true
+}
+
+func (cfg *apiConfig) handlerCreateCollection(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    name, ok := decodeCollection(w, r)
+    if !ok {
+        return
+    }
+    collection, err := cfg.dbQueries.CreateBookmarkCollection(r.Context(), database.CreateBookmarkCollectionParams{
+        UserID: userID,
+        Name:   name,
+    })
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        respondWithError(w, http.StatusConflict, "collection already exists", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating collection", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, collectionFromDB(collection))
+    return
+}
+
+func (cfg *apiConfig) handlerGetCollections(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    collections, err := cfg.dbQueries.GetBookmarkCollectionsByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting collections", err)
+        return
+    }
+    items := make([]BookmarkCollection, 0, len(collections))
+    for _, collection := range collections {
+        items = append(items, collectionFromDB(collection))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerRenameCollection(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    collectionID, ok := parsePathUUID(w, r, "collection_id")
+    if !ok {
+        return
+    }
+    name, ok := decodeCollection(w, r)
+    if !ok {
+        return
+    }
+    collection, err := cfg.dbQueries.RenameBookmarkCollection(r.Context(), database.RenameBookmarkCollectionParams{
+        Name:   name,
+        ID:     collectionID,
+        UserID: userID,
+    })
+    var pqErr *pq.Error
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "collection not found", err)
+        return
+    } else if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        respondWithError(w, http.StatusConflict, "collection already exists", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error renaming collection", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, collectionFromDB(collection))
+    return
+}
+
+func (cfg *apiConfig) handlerDeleteCollection(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    collectionID, ok := parsePathUUID(w, r, "collection_id")
+    if !ok {
+        return
+    }
+
+    // its bookmarks are kept, unfiled
+    n, err := cfg.dbQueries.DeleteBookmarkCollection(r.Context(), database.DeleteBookmarkCollectionParams{
+        ID:     collectionID,
+        UserID: userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting collection", err)
+        return
+    }
+    if n == 0 {
+        respondWithError(w, http.StatusNotFound, "collection not found", nil)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
@@ -1,45 +1,69 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
-	"strings"
 	"time"
 
-	"github.com/CraigYanitski/server-test/internal/auth"
 	"github.com/CraigYanitski/server-test/internal/database"
 	"github.com/google/uuid"
 )
 
+// chirp struct to unmarshal POST requests
+type InitChirp struct {
+    Body       string         `json:"body"`
+    QuoteOf    uuid.NullUUID  `json:"quote_of"`
+    ReplyTo    uuid.NullUUID  `json:"reply_to"`
+    MediaIDs   []uuid.UUID    `json:"media_ids"`
+    PublishAt  *time.Time     `json:"publish_at"`
+    Visibility string         `json:"visibility"`
+    Poll       *InitPoll      `json:"poll"`
+}
+
 type Chirp struct {
-    ID         uuid.UUID  `json:"id"`
-    CreatedAt  time.Time  `json:"created_at"`
-    UpdatedAt  time.Time  `json:"updated_at"`
-    Body       string     `json:"body"`
-    UserID     uuid.UUID  `json:"user_id"`
+    ID              uuid.UUID         `json:"id"`
+    CreatedAt       time.Time         `json:"created_at"`
+    UpdatedAt       time.Time         `json:"updated_at"`
+    Body            string            `json:"body"`
+    UserID          uuid.UUID         `json:"user_id"`
+    Author          *AuthorSummary    `json:"author,omitempty"`
+    LikeCount       int32             `json:"like_count"`
+    LikedByMe       bool              `json:"liked_by_me"`
+    BookmarkedByMe  bool              `json:"bookmarked_by_me"`
+    RechirpOf       *Chirp            `json:"rechirp_of,omitempty"`
+    QuoteOf         *QuotedChirp      `json:"quote_of,omitempty"`
+    ReplyTo         *uuid.UUID        `json:"reply_to,omitempty"`
+    ThreadID        *uuid.UUID        `json:"thread_id,omitempty"`
+    RechirpCount    int32             `json:"rechirp_count"`
+    QuoteCount      int32             `json:"quote_count"`
+    ReplyCount      int32             `json:"reply_count"`
+    Entities        ChirpEntities     `json:"entities"`
+    Edited          bool              `json:"edited"`
+    Moderation      *ChirpModeration  `json:"moderation,omitempty"`
+    Media           []ChirpMedia      `json:"media"`
+    DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+    Visibility      string            `json:"visibility"`
+    Poll            *ChirpPoll        `json:"poll,omitempty"`
+    Pinned          bool              `json:"pinned,omitempty"`
 }
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
     // type chirpError struct {Error string `json:"error"`}
 
     // check user authentication
-    token, err := auth.GetBearerToken(r.Header)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "", err)
-        return
-    }
-    id, err := auth.ValidateJWT(token, cfg.secret)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, token, err)
+    id, ok := cfg.authenticateUser(w, r)
+    if !ok {
         return
     }
 
     // decode request body
     decoder := json.NewDecoder(r.Body)
-    chp := &Chirp{}
-    err = decoder.Decode(chp)
+    chp := &InitChirp{}
+    err := decoder.Decode(chp)
     if err != nil {
         //fmt.Printf("error decoding a JSON: %s\n", err)
         //w.WriteHeader(500)
@@ -47,51 +71,54 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    // validate chirp length and sanitize
-    var cleanChirp string
-    if len(chp.Body) > 140 {
-        respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
+    // chirps for later are saved as scheduled drafts
+    if chp.PublishAt != nil && chp.PublishAt.After(time.Now()) {
+        cfg.saveDraft(w, r, id, uuid.Nil, chp)
         return
-    } else {
-        cleanChirp = CleanChirpBody(chp.Body)
     }
 
-    // create chirp
-    params := database.CreateChirpParams{
-        Body: cleanChirp, 
-        UserID: id,
+    // validate the chirp
+    params, mediaIDs, err := cfg.prepareChirp(r.Context(), id, chp)
+    if err != nil {
+        respondWithChirpError(w, "error checking chirp", err)
+        return
     }
-    chirp, err := cfg.dbQueries.CreateChirp(r.Context(), params)
+
+    // create chirp, counting the quote on the original in the same transaction
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
     if err != nil {
-        respondWithError(w, http.StatusInternalServerError, "error creating chirp", err)
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
         return
     }
-    respondWithJSON(w, http.StatusCreated, Chirp(chirp))
-    return
-}
+    defer tx.Rollback()
+    chirp, err := cfg.createChirp(r.Context(), cfg.dbQueries.WithTx(tx), params, mediaIDs, chp.Poll)
+    if err != nil {
+        respondWithChirpError(w, "error creating chirp", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing chirp", err)
+        return
+    }
+    cfg.notifyNewChirp(r.Context(), chirp)
 
-func CleanChirpBody(body string) string {
-    clean_body := []string{}
-    badWords := map[string]struct{} {
-        "kerfuffle": {},
-        "sharbert":  {},
-        "fornax":    {},
-    }
-    for _, word := range strings.Fields(body) {
-        if _, ok := badWords[strings.ToLower(word)]; ok {
-                clean_body = append(clean_body, "****")
-            } else {
-                clean_body = append(clean_body, word)
-            }
-        }
-    return strings.Join(clean_body, " ")
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: id, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, item)
+    return
 }
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
     // define slice of chirps otherwise
     var err error
     var chirps []database.Chirp
-    items := []Chirp{}
+    var authorID uuid.NullUUID
 
     // check if user is specified
     idQuery := r.URL.Query().Get("author_id")
@@ -106,6 +133,7 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
             respondWithError(w, http.StatusNotFound, fmt.Sprintf("%s chirps not found", userID), err)
             return
         }
+        authorID = uuid.NullUUID{UUID: userID, Valid: true}
     } else {
         // otherwise return chirps ordered by time
         chirps, err = cfg.dbQueries.GetChirps(r.Context())
@@ -115,9 +143,13 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
         }
     }
 
-    // recast the slice of chirps
-    for _, item := range chirps {
-        items = append(items, Chirp(item))
+    // recast the slice of chirps with the viewer's like state, leaving out
+    // blocked and muted authors
+    viewer := cfg.viewerID(r)
+    items, err := cfg.listChirps(r.Context(), viewer, chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
     }
 
     // reorder as needed
@@ -126,34 +158,66 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
         slices.Reverse(items)
     }
 
+    // an author's pinned chirps come first whatever the order
+    if authorID.Valid {
+        items, err = cfg.withPinnedChirps(r.Context(), viewer, authorID.UUID, items)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error getting pinned chirps", err)
+            return
+        }
+    }
+
     respondWithJSON(w, http.StatusOK, items)
     return
 }
 
+func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    cfg.getSingleChirp(w, r, chirpID)
+}
+
 func (cfg *apiConfig) getSingleChirp(w http.ResponseWriter, r *http.Request, chirpID uuid.UUID) {
     chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
     if err != nil {
         respondWithError(w, http.StatusNotFound, "error finding chirp", err)
         return
     }
-    respondWithJSON(w, http.StatusOK, Chirp(chirp))
+
+    // chirps the viewer cannot see are not found
+    viewer := cfg.viewerID(r)
+    ok, err := cfg.canViewChirp(r.Context(), viewer, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "error finding chirp", nil)
+        return
+    }
+    item, err := cfg.hydrateChirp(r.Context(), viewer, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
     return
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
     // authenticate user
-    token, err := auth.GetBearerToken(r.Header)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "missing access token", err)
-        return
-    }
-    userID, err := auth.ValidateJWT(token, cfg.secret)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "invalid JWT", err)
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
         return
     }
 
     // get chirp information
+    var err error
     id := r.PathValue("chirp_id")
     var chirpID uuid.UUID
     if id == "" {
@@ -178,12 +242,56 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    // remove chirp and respond with success
-    _, err = cfg.dbQueries.DeleteChirp(r.Context(), chirpID)
+    // remove chirp
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    err = deleteChirp(r.Context(), cfg.dbQueries.WithTx(tx), chirp)
     if err != nil {
         respondWithError(w, http.StatusInternalServerError, "error deleting chirp", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing delete", err)
+        return
     }
+
+    // respond with success
     respondWithJSON(w, http.StatusNoContent, nil)
     return
 }
 
+// soft delete a chirp within a transaction, unpinning it, announcing the
+// deletion and dropping the counts on anything it pointed at. It disappears
+// from every read, including its rechirps, and quotes of it show a
+// placeholder until it is restored or purged.
+func deleteChirp(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+    _, err := qtx.SoftDeleteChirp(ctx, chirp.ID)
+    if err != nil {
+        return err
+    }
+    err = qtx.DeleteChirpPin(ctx, chirp.ID)
+    if err != nil {
+        return err
+    }
+    err = publishEvent(ctx, qtx, eventChirpDeleted, chirpEventData(chirp))
+    if err != nil {
+        return err
+    }
+    if chirp.RechirpOf.Valid {
+        return qtx.DecrementChirpRechirps(ctx, chirp.RechirpOf.UUID)
+    }
+    if chirp.QuoteOf.Valid {
+        err = qtx.DecrementChirpQuotes(ctx, chirp.QuoteOf.UUID)
+        if err != nil {
+            return err
+        }
+    }
+    if chirp.ReplyTo.Valid {
+        return qtx.DecrementChirpReplies(ctx, chirp.ReplyTo.UUID)
+    }
+    return nil
+}
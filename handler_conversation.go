@@ -0,0 +1,451 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// the other participants of a new conversation. With one other participant
+// the one-to-one conversation between the two is reused.
+type InitConversation struct {
+    ParticipantIDs  []uuid.UUID  `json:"participant_ids"`
+}
+
+type InitMessage struct {
+    Body  string  `json:"body"`
+}
+
+// read receipt up to a message, or the latest one if none is given
+type InitReadReceipt struct {
+    MessageID  uuid.NullUUID  `json:"message_id"`
+}
+
+type ConversationPage struct {
+    Conversations  []Conversation  `json:"conversations"`
+    NextCursor     string          `json:"next_cursor,omitempty"`
+}
+
+type MessagePage struct {
+    Messages    []Message  `json:"messages"`
+    NextCursor  string     `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    init := InitConversation{}
+    err := decoder.Decode(&init)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+
+    // check the other participants exist
+    others := []uuid.UUID{}
+    for _, id := range init.ParticipantIDs {
+        if id != userID && !slices.Contains(others, id) {
+            others = append(others, id)
+        }
+    }
+    if len(others) == 0 {
+        respondWithError(w, http.StatusBadRequest, "a conversation needs another participant", nil)
+        return
+    }
+    if len(others)+1 > maxConversationParticipants {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("a conversation can have at most %d participants", maxConversationParticipants), nil)
+        return
+    }
+    existing, err := cfg.dbQueries.GetExistingUserIDs(r.Context(), others)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting users", err)
+        return
+    }
+    if len(existing) != len(others) {
+        respondWithError(w, http.StatusNotFound, "user not found", nil)
+        return
+    }
+    // nobody in it can have blocked anyone else in it, or they could never
+    // send a message
+    blocked, err := cfg.dbQueries.IsBlockedWithin(r.Context(), append([]uuid.UUID{userID}, others...))
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks", err)
+        return
+    }
+    if blocked {
+        respondWithError(w, http.StatusForbidden, "cannot start a conversation with these users", nil)
+        return
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+
+    // one-to-one conversations are reused, bringing the user back if they
+    // had left
+    status := http.StatusCreated
+    var conversation database.Conversation
+    if len(others) == 1 {
+        key := sql.NullString{String: directKey(userID, others[0]), Valid: true}
+        conversation, err = qtx.GetDirectConversation(r.Context(), key)
+        if err == nil {
+            status = http.StatusOK
+        } else if errors.Is(err, sql.ErrNoRows) {
+            conversation, err = qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+                CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+                DirectKey: key,
+            })
+        }
+    } else {
+        conversation, err = qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+            CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+            IsGroup:   true,
+        })
+    }
+    if isUniqueViolation(err) {
+        respondWithError(w, http.StatusConflict, "conversation was just started, try again", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating conversation", err)
+        return
+    }
+    joining := []uuid.UUID{userID}
+    if status == http.StatusCreated {
+        joining = append(joining, others...)
+    }
+    err = qtx.AddConversationParticipants(r.Context(), database.AddConversationParticipantsParams{
+        ConversationID: conversation.ID,
+        UserIds:        joining,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error adding participants", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing conversation", err)
+        return
+    }
+
+    items, err := cfg.recastConversations(r.Context(), userID, []database.Conversation{conversation})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation data", err)
+        return
+    }
+    respondWithJSON(w, status, items[0])
+    return
+}
+
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    conversations, err := cfg.dbQueries.GetConversationsForUser(r.Context(), database.GetConversationsForUserParams{
+        UserID:          userID,
+        BeforeUpdatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversations", err)
+        return
+    }
+    items, err := cfg.recastConversations(r.Context(), userID, conversations)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation data", err)
+        return
+    }
+
+    page := ConversationPage{Conversations: items}
+    if len(conversations) == int(limit) {
+        last := conversations[len(conversations)-1]
+        page.NextCursor = encodeCursor(last.UpdatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
+
+func (cfg *apiConfig) handlerGetConversation(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    conversation, err := cfg.dbQueries.GetConversation(r.Context(), participant.ConversationID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation", err)
+        return
+    }
+    items, err := cfg.recastConversations(r.Context(), userID, []database.Conversation{conversation})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items[0])
+    return
+}
+
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    msg := InitMessage{}
+    err := decoder.Decode(&msg)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    body := chirptext.Normalize(strings.TrimSpace(msg.Body))
+    if body == "" {
+        respondWithError(w, http.StatusBadRequest, "message cannot be empty", nil)
+        return
+    }
+    if length := utf8.RuneCountInString(body); length > maxMessageLength {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("message is too long: %d characters, at most %d allowed", length, maxMessageLength), nil)
+        return
+    }
+
+    conversation, err := cfg.dbQueries.GetConversation(r.Context(), participant.ConversationID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting conversation", err)
+        return
+    }
+    participants, err := cfg.dbQueries.GetConversationParticipants(r.Context(), []uuid.UUID{conversation.ID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting participants", err)
+        return
+    }
+
+    // the other side of a one-to-one conversation gets it back even if they
+    // left it, while groups only reach those still in them
+    all := []uuid.UUID{}
+    others := []uuid.UUID{}
+    recipients := []uuid.UUID{}
+    for _, other := range participants {
+        all = append(all, other.UserID)
+        if other.UserID == userID || (conversation.IsGroup && other.LeftAt.Valid) {
+            continue
+        }
+        others = append(others, other.UserID)
+        if !other.Muted {
+            recipients = append(recipients, other.UserID)
+        }
+    }
+
+    // nobody can message someone they blocked or who blocked them
+    blocked, err := cfg.dbQueries.IsBlockedAmong(r.Context(), database.IsBlockedAmongParams{UserID: userID, OtherIds: others})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks", err)
+        return
+    }
+    if blocked {
+        respondWithError(w, http.StatusForbidden, "cannot message users you blocked or who blocked you", nil)
+        return
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    message, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams{
+        ConversationID: conversation.ID,
+        SenderID:       uuid.NullUUID{UUID: userID, Valid: true},
+        Body:           body,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating message", err)
+        return
+    }
+    err = qtx.TouchConversation(r.Context(), conversation.ID)
+    if err == nil && !conversation.IsGroup {
+        err = qtx.AddConversationParticipants(r.Context(), database.AddConversationParticipantsParams{
+            ConversationID: conversation.ID,
+            UserIds:        all,
+        })
+    }
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating conversation", err)
+        return
+    }
+
+    // the sender has read their own message
+    _, err = qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+        ConversationID: conversation.ID,
+        MessageID:      uuid.NullUUID{UUID: message.ID, Valid: true},
+        UserID:         userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating read receipt", err)
+        return
+    }
+    if len(recipients) > 0 {
+        err = publishEvent(r.Context(), qtx, eventMessageCreated, eventData{MessageID: message.ID, UserID: userID, RecipientIDs: recipients})
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error announcing message", err)
+            return
+        }
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing message", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, messageFromDB(message))
+    return
+}
+
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    messages, err := cfg.dbQueries.GetMessages(r.Context(), database.GetMessagesParams{
+        ConversationID:  participant.ConversationID,
+        BeforeCreatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting messages", err)
+        return
+    }
+
+    page := MessagePage{Messages: make([]Message, 0, len(messages))}
+    for _, message := range messages {
+        page.Messages = append(page.Messages, messageFromDB(message))
+    }
+    if len(messages) == int(limit) {
+        last := messages[len(messages)-1]
+        page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
+
+func (cfg *apiConfig) handlerReadConversation(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    receipt := InitReadReceipt{}
+    if r.ContentLength != 0 {
+        decoder := json.NewDecoder(r.Body)
+        if err := decoder.Decode(&receipt); err != nil {
+            respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+            return
+        }
+    }
+
+    // receipts only move forward, so an older message changes nothing
+    _, err := cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+        ConversationID: participant.ConversationID,
+        MessageID:      receipt.MessageID,
+        UserID:         userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating read receipt", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerLeaveConversation(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.LeaveConversation(r.Context(), database.LeaveConversationParams{
+        ConversationID: participant.ConversationID,
+        UserID:         userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error leaving conversation", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerMuteConversation(w http.ResponseWriter, r *http.Request) {
+    cfg.setConversationMuted(w, r, true)
+    return
+}
+
+func (cfg *apiConfig) handlerUnmuteConversation(w http.ResponseWriter, r *http.Request) {
+    cfg.setConversationMuted(w, r, false)
+    return
+}
+
+// muted conversations still count unread messages, but new ones are not
+// pushed to the user's streams
+func (cfg *apiConfig) setConversationMuted(w http.ResponseWriter, r *http.Request, muted bool) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    participant, ok := cfg.activeParticipant(w, r, userID)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.SetConversationMuted(r.Context(), database.SetConversationMutedParams{
+        Muted:          muted,
+        ConversationID: participant.ConversationID,
+        UserID:         userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error muting conversation", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/auth"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+type DigestSettings struct {
+    Frequency  string  `json:"frequency"`
+}
+
+func (cfg *apiConfig) handlerGetDigestSettings(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    user, err := cfg.dbQueries.GetUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, DigestSettings{Frequency: user.DigestFrequency})
+    return
+}
+
+func (cfg *apiConfig) handlerUpdateDigestSettings(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    settings := DigestSettings{}
+    err := decoder.Decode(&settings)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if _, ok := digestPeriods[settings.Frequency]; !ok && settings.Frequency != digestOff {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown digest frequency '%s'", settings.Frequency), nil)
+        return
+    }
+    user, err := cfg.dbQueries.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+        DigestFrequency: settings.Frequency,
+        ID:              userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating digest settings", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, DigestSettings{Frequency: user.DigestFrequency})
+    return
+}
+
+// the unsubscribe link in digests asks before unsubscribing, since mail
+// scanners follow links. Mail clients POST to it directly.
+func (cfg *apiConfig) handlerUnsubscribePage(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.checkUnsubscribeLink(w, r)
+    if !ok {
+        return
+    }
+    cfg.respondWithUnsubscribePage(w, userID, false)
+    return
+}
+
+func (cfg *apiConfig) handlerUnsubscribe(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.checkUnsubscribeLink(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+        DigestFrequency: digestOff,
+        ID:              userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating digest settings", err)
+        return
+    }
+    cfg.respondWithUnsubscribePage(w, userID, true)
+    return
+}
+
+// check the user ID in an unsubscribe link was signed by us
+func (cfg *apiConfig) checkUnsubscribeLink(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+    userID, err := uuid.Parse(r.URL.Query().Get("user_id"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid unsubscribe link", err)
+        return uuid.Nil, false
+    }
+    err = auth.CheckUnsubscribeToken(userID, r.URL.Query().Get("token"), cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusForbidden, "invalid unsubscribe link", err)
+        return uuid.Nil, false
+    }
+    return userID, true
+}
+
+func (cfg *apiConfig) respondWithUnsubscribePage(w http.ResponseWriter, userID uuid.UUID, done bool) {
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.WriteHeader(http.StatusOK)
+    unsubscribePage.Execute(w, struct {
+        Done  bool
+        URL   string
+    }{done, cfg.unsubscribeURL(userID)})
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// draft as seen by its author, scheduled when it has a publish time
+type Draft struct {
+    ID         uuid.UUID    `json:"id"`
+    CreatedAt  time.Time    `json:"created_at"`
+    UpdatedAt  time.Time    `json:"updated_at"`
+    Body       string       `json:"body"`
+    QuoteOf    *uuid.UUID   `json:"quote_of"`
+    ReplyTo    *uuid.UUID   `json:"reply_to"`
+    MediaIDs   []uuid.UUID  `json:"media_ids"`
+    PublishAt  *time.Time   `json:"publish_at"`
+    LastError  string       `json:"last_error,omitempty"`
+    Visibility string       `json:"visibility"`
+}
+
+func draftFromDB(draft database.Draft) Draft {
+    item := Draft{
+        ID:        draft.ID,
+        CreatedAt: draft.CreatedAt,
+        UpdatedAt: draft.UpdatedAt,
+        Body:      draft.Body,
+        MediaIDs:  draft.MediaIds,
+        LastError: draft.LastError.String,
+        Visibility: draft.Visibility,
+    }
+    if item.MediaIDs == nil {
+        item.MediaIDs = []uuid.UUID{}
+    }
+    if draft.QuoteOf.Valid {
+        item.QuoteOf = &draft.QuoteOf.UUID
+    }
+    if draft.ReplyTo.Valid {
+        item.ReplyTo = &draft.ReplyTo.UUID
+    }
+    if draft.PublishAt.Valid {
+        item.PublishAt = &draft.PublishAt.Time
+    }
+    return item
+}
+
+// validate a draft like a chirp and save it, creating it when draftID is nil.
+// Drafts keep the body as written and are moderated again when published.
+func (cfg *apiConfig) saveDraft(w http.ResponseWriter, r *http.Request, userID, draftID uuid.UUID, chp *InitChirp) {
+    // drafts keep no polls, whose closing time would run out before publishing
+    if chp.Poll != nil {
+        respondWithError(w, http.StatusBadRequest, "polls cannot be saved in drafts", nil)
+        return
+    }
+    params, mediaIDs, err := cfg.prepareChirp(r.Context(), userID, chp)
+    if err != nil {
+        respondWithChirpError(w, "error checking draft", err)
+        return
+    }
+    publishAt := sql.NullTime{}
+    if chp.PublishAt != nil {
+        publishAt = sql.NullTime{Time: chp.PublishAt.UTC(), Valid: true}
+    }
+
+    var draft database.Draft
+    status := http.StatusOK
+    if draftID == uuid.Nil {
+        status = http.StatusCreated
+        if publishAt.Valid {
+            status = http.StatusAccepted
+        }
+        draft, err = cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+            UserID:    userID,
+            Body:      chp.Body,
+            QuoteOf:   chp.QuoteOf,
+            MediaIds:   mediaIDs,
+            PublishAt:  publishAt,
+            Visibility: params.Visibility,
+            ReplyTo:    chp.ReplyTo,
+        })
+    } else {
+        // a draft that is being published is locked, so this waits and then
+        // finds nothing to update
+        draft, err = cfg.dbQueries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+            ID:        draftID,
+            UserID:    userID,
+            Body:      chp.Body,
+            QuoteOf:   chp.QuoteOf,
+            MediaIds:   mediaIDs,
+            PublishAt:  publishAt,
+            Visibility: params.Visibility,
+            ReplyTo:    chp.ReplyTo,
+        })
+        if errors.Is(err, sql.ErrNoRows) {
+            respondWithError(w, http.StatusNotFound, "draft not found or already published", err)
+            return
+        }
+    }
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error saving draft", err)
+        return
+    }
+    respondWithJSON(w, status, draftFromDB(draft))
+    return
+}
+
+func (cfg *apiConfig) handlerCreateDraft(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+
+    // decode request body
+    decoder := json.NewDecoder(r.Body)
+    chp := &InitChirp{}
+    err := decoder.Decode(chp)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    cfg.saveDraft(w, r, userID, uuid.Nil, chp)
+    return
+}
+
+func (cfg *apiConfig) handlerGetDrafts(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+
+    // scheduled drafts first, soonest first
+    drafts, err := cfg.dbQueries.GetDraftsByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting drafts", err)
+        return
+    }
+    items := make([]Draft, 0, len(drafts))
+    for _, draft := range drafts {
+        items = append(items, draftFromDB(draft))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerGetDraft(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    draftID, ok := parsePathUUID(w, r, "draft_id")
+    if !ok {
+        return
+    }
+    draft, err := cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "draft not found", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, draftFromDB(draft))
+    return
+}
+
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    draftID, ok := parsePathUUID(w, r, "draft_id")
+    if !ok {
+        return
+    }
+
+    // decode request body, which replaces the draft
+    decoder := json.NewDecoder(r.Body)
+    chp := &InitChirp{}
+    err := decoder.Decode(chp)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    cfg.saveDraft(w, r, userID, draftID, chp)
+    return
+}
+
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    draftID, ok := parsePathUUID(w, r, "draft_id")
+    if !ok {
+        return
+    }
+
+    // deleting a scheduled draft cancels it, unless it is already published
+    deleted, err := cfg.dbQueries.DeleteDraft(r.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting draft", err)
+        return
+    }
+    if deleted == 0 {
+        respondWithError(w, http.StatusNotFound, "draft not found or already published", nil)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+    // hashtags are stored lowercase and without the leading '#'
+    tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+    if tag == "" {
+        respondWithError(w, http.StatusBadRequest, "no hashtag given", nil)
+        return
+    }
+
+    chirps, err := cfg.dbQueries.GetChirpsByHashtag(r.Context(), tag)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting hashtag chirps", err)
+        return
+    }
+    items, err := cfg.listChirps(r.Context(), cfg.viewerID(r), chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerGetUserMentions(w http.ResponseWriter, r *http.Request) {
+    userID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return
+    }
+
+    chirps, err := cfg.dbQueries.GetChirpsMentioningUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting mentions", err)
+        return
+    }
+    items, err := cfg.listChirps(r.Context(), cfg.viewerID(r), chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+
+    // users who have blocked each other cannot follow each other
+    blocked, err := cfg.isBlocked(r.Context(), userID, targetID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks", err)
+        return
+    }
+    if blocked {
+        respondWithError(w, http.StatusForbidden, "cannot follow this user", nil)
+        return
+    }
+    n, err := cfg.dbQueries.FollowUser(r.Context(), database.FollowUserParams{FollowerID: userID, FolloweeID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error following user", err)
+        return
+    }
+    if n > 0 {
+        err = notify(r.Context(), cfg.dbQueries, targetID, notifyFollow, uuid.NullUUID{}, userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error notifying user", err)
+            return
+        }
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+    userID, targetID, ok := cfg.relationTarget(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: userID, FolloweeID: targetID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error unfollowing user", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    ok, err = cfg.canViewChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // add the like and notify the author in one transaction. The counter is
+    // kept by a trigger on the likes.
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    added, err := qtx.LikeChirp(r.Context(), database.LikeChirpParams{ChirpID: chirpID, UserID: userID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error liking chirp", err)
+        return
+    }
+    if added > 0 {
+        chirp, err = qtx.GetChirp(r.Context(), chirpID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error reading like count", err)
+            return
+        }
+        err = notify(r.Context(), qtx, chirp.UserID, notifyLike, uuid.NullUUID{UUID: chirpID, Valid: true}, userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error notifying author", err)
+            return
+        }
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing like", err)
+        return
+    }
+
+    // respond with the updated chirp
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+
+    // remove the like, then read the count the trigger dropped
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    removed, err := qtx.UnlikeChirp(r.Context(), database.UnlikeChirpParams{ChirpID: chirpID, UserID: userID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error unliking chirp", err)
+        return
+    }
+    if removed > 0 {
+        chirp, err = qtx.GetChirp(r.Context(), chirpID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error reading like count", err)
+            return
+        }
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing unlike", err)
+        return
+    }
+
+    // respond with the updated chirp
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerGetUserLikes(w http.ResponseWriter, r *http.Request) {
+    userID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return
+    }
+
+    // get chirps liked by the user, most recent like first
+    chirps, err := cfg.dbQueries.GetChirpsLikedByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting liked chirps", err)
+        return
+    }
+    items, err := cfg.listChirps(r.Context(), cfg.viewerID(r), chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/media"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+
+    // read the file from the multipart form, allowing a little room for the
+    // form itself
+    r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes+(64<<10))
+    file, _, err := r.FormFile("file")
+    if err != nil {
+        var maxErr *http.MaxBytesError
+        if errors.As(err, &maxErr) {
+            respondWithError(w, http.StatusRequestEntityTooLarge, "upload too large", err)
+            return
+        }
+        respondWithError(w, http.StatusBadRequest, "expected an image in the 'file' form field", err)
+        return
+    }
+    defer file.Close()
+    data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadBytes+1))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error reading upload", err)
+        return
+    }
+
+    // validate and re-encode the image, which strips its metadata
+    img, err := media.Process(data)
+    if errors.Is(err, media.ErrTooLarge) {
+        respondWithError(w, http.StatusRequestEntityTooLarge, "image too large", err)
+        return
+    } else if errors.Is(err, media.ErrUnsupportedType) {
+        respondWithError(w, http.StatusUnsupportedMediaType, "only JPEG, PNG and GIF images are accepted", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid image", err)
+        return
+    }
+
+    // store the blobs before recording them
+    id := uuid.New()
+    blobKey := fmt.Sprintf("media/%s.%s", id, img.Extension)
+    thumbnailKey := fmt.Sprintf("thumbnails/%s.%s", id, img.Extension)
+    if err = cfg.blobs.Put(r.Context(), blobKey, img.ContentType, img.Data); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error storing image", err)
+        return
+    }
+    if err = cfg.blobs.Put(r.Context(), thumbnailKey, img.ContentType, img.Thumbnail); err != nil {
+        cfg.blobs.Delete(r.Context(), blobKey)
+        respondWithError(w, http.StatusInternalServerError, "error storing thumbnail", err)
+        return
+    }
+    attachment, err := cfg.dbQueries.CreateMediaAttachment(r.Context(), database.CreateMediaAttachmentParams{
+        ID:           id,
+        UserID:       userID,
+        ContentType:  img.ContentType,
+        Width:        int32(img.Width),
+        Height:       int32(img.Height),
+        SizeBytes:    int32(len(img.Data)),
+        BlobKey:      blobKey,
+        ThumbnailKey: thumbnailKey,
+    })
+    if err != nil {
+        cfg.removeMediaBlobs(r.Context(), []database.MediaAttachment{{BlobKey: blobKey, ThumbnailKey: thumbnailKey}})
+        respondWithError(w, http.StatusInternalServerError, "error saving media", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusCreated, chirpMediaFromDB(attachment))
+    return
+}
+
+func (cfg *apiConfig) handlerGetMedia(w http.ResponseWriter, r *http.Request) {
+    cfg.serveMedia(w, r, false)
+}
+
+func (cfg *apiConfig) handlerGetMediaThumbnail(w http.ResponseWriter, r *http.Request) {
+    cfg.serveMedia(w, r, true)
+}
+
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+    mediaID, ok := parsePathUUID(w, r, "media_id")
+    if !ok {
+        return
+    }
+    attachment, err := cfg.dbQueries.GetMediaAttachment(r.Context(), mediaID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "media not found", err)
+        return
+    }
+    public, ok := cfg.canViewMedia(w, r, attachment)
+    if !ok {
+        return
+    }
+    key := attachment.BlobKey
+    if thumbnail {
+        key = attachment.ThumbnailKey
+    }
+    blob, err := cfg.blobs.Get(r.Context(), key)
+    if errors.Is(err, media.ErrNotFound) {
+        respondWithError(w, http.StatusNotFound, "media not found", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error reading media", err)
+        return
+    }
+    defer blob.Close()
+
+    // blobs never change, so they can be cached for good, but only by the
+    // viewer unless anyone could see them
+    w.Header().Set("Content-Type", attachment.ContentType)
+    if public {
+        w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+    } else {
+        w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+    }
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.WriteHeader(http.StatusOK)
+    io.Copy(w, blob)
+}
+
+// check the viewer can see media, responding with not found if not, and
+// whether anyone can. Media goes with the chirp it is attached to, avatars
+// are public and uploads not used yet are only for their owner.
+func (cfg *apiConfig) canViewMedia(w http.ResponseWriter, r *http.Request, attachment database.MediaAttachment) (bool, bool) {
+    viewer := cfg.viewerID(r)
+    if attachment.ChirpID.Valid {
+        chirp, err := cfg.dbQueries.GetChirp(r.Context(), attachment.ChirpID.UUID)
+        if err != nil {
+            respondWithError(w, http.StatusNotFound, "media not found", err)
+            return false, false
+        }
+        ok, err := cfg.canViewChirp(r.Context(), viewer, chirp)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+            return false, false
+        }
+        if !ok {
+            respondWithError(w, http.StatusNotFound, "media not found", nil)
+            return false, false
+        }
+        public := !restrictedVisibility(chirp.Visibility) && chirp.ModerationState == chirpStateVisible
+        return public, true
+    }
+
+    owner, err := cfg.dbQueries.GetUser(r.Context(), attachment.UserID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "media not found", err)
+        return false, false
+    }
+    if owner.AvatarMediaID.Valid && owner.AvatarMediaID.UUID == attachment.ID {
+        return true, true
+    }
+    if !viewer.Valid || viewer.UUID != attachment.UserID {
+        respondWithError(w, http.StatusNotFound, "media not found", nil)
+        return false, false
+    }
+    return false, true
+}
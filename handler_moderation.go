@@ -0,0 +1,475 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// actions a moderator can resolve a report with
+const (
+    resolveDismiss        = "dismiss"
+    resolveHideChirp      = "hide_chirp"
+    resolveDeleteChirp    = "delete_chirp"
+    resolveSuspendAuthor  = "suspend_author"
+)
+
+// report status after it is resolved
+const (
+    reportOpen       = "open"
+    reportDismissed  = "dismissed"
+    reportActioned   = "actioned"
+)
+
+// resolution struct to unmarshal POST requests, a suspension without days is
+// permanent
+type InitResolution struct {
+    Action       string  `json:"action"`
+    Note         string  `json:"note"`
+    SuspendDays  int     `json:"suspend_days"`
+}
+
+// report as seen by moderators
+type ModeratorReport struct {
+    Report
+    ReporterID      uuid.UUID   `json:"reporter_id"`
+    ChirpAuthorID   uuid.UUID   `json:"chirp_author_id"`
+    ChirpBody       string      `json:"chirp_body"`
+    ResolutionNote  string      `json:"resolution_note,omitempty"`
+    ResolvedBy      *uuid.UUID  `json:"resolved_by,omitempty"`
+}
+
+type ModerationAction struct {
+    ID            uuid.UUID   `json:"id"`
+    CreatedAt     time.Time   `json:"created_at"`
+    ModeratorID   *uuid.UUID  `json:"moderator_id"`
+    Action        string      `json:"action"`
+    TargetUserID  *uuid.UUID  `json:"target_user_id,omitempty"`
+    ReportID      *uuid.UUID  `json:"report_id,omitempty"`
+    Note          string      `json:"note"`
+}
+
+// everything a moderator needs to decide on a report
+type ReportContext struct {
+    Report                 ModeratorReport     `json:"report"`
+    Chirp                  *Chirp              `json:"chirp"`
+    OtherReports           []ModeratorReport   `json:"other_reports"`
+    Actions                []ModerationAction  `json:"actions"`
+    AuthorActionedReports  int64               `json:"author_actioned_reports"`
+    AuthorSuspended        bool                `json:"author_suspended"`
+}
+
+func moderatorReportFromDB(report database.Report) ModeratorReport {
+    item := ModeratorReport{
+        Report:         reportFromDB(report),
+        ReporterID:     report.ReporterID,
+        ChirpAuthorID:  report.ChirpAuthorID,
+        ChirpBody:      report.ChirpBody,
+        ResolutionNote: report.ResolutionNote.String,
+    }
+    if report.ResolvedBy.Valid {
+        item.ResolvedBy = &report.ResolvedBy.UUID
+    }
+    return item
+}
+
+func moderationActionFromDB(action database.ModerationAction) ModerationAction {
+    item := ModerationAction{
+        ID:        action.ID,
+        CreatedAt: action.CreatedAt,
+        Action:    action.Action,
+        Note:      action.Note,
+    }
+    if action.ModeratorID.Valid {
+        item.ModeratorID = &action.ModeratorID.UUID
+    }
+    if action.TargetUserID.Valid {
+        item.TargetUserID = &action.TargetUserID.UUID
+    }
+    if action.ReportID.Valid {
+        item.ReportID = &action.ReportID.UUID
+    }
+    return item
+}
+
+func (cfg *apiConfig) handlerGetReportQueue(w http.ResponseWriter, r *http.Request) {
+    if _, ok := cfg.authenticateModerator(w, r); !ok {
+        return
+    }
+
+    // list open reports oldest first, or another status if asked for
+    status := r.URL.Query().Get("status")
+    if status == "" {
+        status = reportOpen
+    }
+    reports, err := cfg.dbQueries.GetReportsByStatus(r.Context(), status)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting reports", err)
+        return
+    }
+    items := make([]ModeratorReport, 0, len(reports))
+    for _, report := range reports {
+        items = append(items, moderatorReportFromDB(report))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerGetReportContext(w http.ResponseWriter, r *http.Request) {
+    moderatorID, ok := cfg.authenticateModerator(w, r)
+    if !ok {
+        return
+    }
+    reportID, ok := parsePathUUID(w, r, "report_id")
+    if !ok {
+        return
+    }
+    report, err := cfg.dbQueries.GetReport(r.Context(), reportID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "report not found", err)
+        return
+    }
+    rc := ReportContext{Report: moderatorReportFromDB(report)}
+
+    // current state of the chirp, including soft deletes, which is null once
+    // purged
+    chirp, err := cfg.dbQueries.GetChirpIncludingDeleted(r.Context(), report.ChirpID)
+    if err == nil {
+        items, err := cfg.recastChirps(r.Context(), uuid.NullUUID{UUID: moderatorID, Valid: true}, []database.Chirp{chirp})
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+            return
+        }
+        item := items[0]
+        item.Moderation = &ChirpModeration{State: chirp.ModerationState, Rules: chirp.ModerationRules}
+        rc.Chirp = &item
+    } else if !errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp", err)
+        return
+    }
+
+    // the other reports on the chirp and the actions already taken on it
+    others, err := cfg.dbQueries.GetReportsForChirp(r.Context(), report.ChirpID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting reports", err)
+        return
+    }
+    rc.OtherReports = []ModeratorReport{}
+    for _, other := range others {
+        if other.ID != report.ID {
+            rc.OtherReports = append(rc.OtherReports, moderatorReportFromDB(other))
+        }
+    }
+    actions, err := cfg.dbQueries.GetModerationActionsForChirp(r.Context(), uuid.NullUUID{UUID: report.ChirpID, Valid: true})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting moderation actions", err)
+        return
+    }
+    rc.Actions = []ModerationAction{}
+    for _, action := range actions {
+        rc.Actions = append(rc.Actions, moderationActionFromDB(action))
+    }
+
+    // the author's record
+    rc.AuthorActionedReports, err = cfg.dbQueries.CountActionedReportsForAuthor(r.Context(), report.ChirpAuthorID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error counting author reports", err)
+        return
+    }
+    _, err = cfg.dbQueries.GetActiveSuspension(r.Context(), report.ChirpAuthorID)
+    if err != nil && !errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusInternalServerError, "error checking author suspension", err)
+        return
+    }
+    rc.AuthorSuspended = err == nil
+
+    respondWithJSON(w, http.StatusOK, rc)
+    return
+}
+
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, r *http.Request) {
+    moderatorID, ok := cfg.authenticateModerator(w, r)
+    if !ok {
+        return
+    }
+    reportID, ok := parsePathUUID(w, r, "report_id")
+    if !ok {
+        return
+    }
+
+    // decode request body
+    decoder := json.NewDecoder(r.Body)
+    res := &InitResolution{}
+    err := decoder.Decode(res)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    report, err := cfg.dbQueries.GetReport(r.Context(), reportID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "report not found", err)
+        return
+    }
+    if report.Status != reportOpen {
+        respondWithError(w, http.StatusConflict, "report already resolved", nil)
+        return
+    }
+
+    // apply the action and resolve every open report on the chirp together
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    status := reportActioned
+    switch res.Action {
+    case resolveDismiss:
+        status = reportDismissed
+    case resolveHideChirp:
+        var chirp, hidden database.Chirp
+        chirp, err = qtx.GetChirpIncludingDeleted(r.Context(), report.ChirpID)
+        if err == nil {
+            hidden, err = qtx.SetChirpModerationState(r.Context(), database.SetChirpModerationStateParams{
+                ID:              report.ChirpID,
+                ModerationState: chirpStateHidden,
+            })
+        }
+        if err == nil {
+            err = announceModeration(r.Context(), qtx, chirp, hidden)
+        }
+    case resolveDeleteChirp:
+        // hide it as well, so the author cannot restore it. The deletion is
+        // announced with the chirp as it was, so whoever saw it hears of it.
+        var chirp database.Chirp
+        chirp, err = qtx.GetChirpIncludingDeleted(r.Context(), report.ChirpID)
+        if err == nil {
+            _, err = qtx.SetChirpModerationState(r.Context(), database.SetChirpModerationStateParams{
+                ID:              report.ChirpID,
+                ModerationState: chirpStateHidden,
+            })
+        }
+        // a chirp the author already deleted only needed hiding
+        if err == nil && !chirp.DeletedAt.Valid {
+            err = deleteChirp(r.Context(), qtx, chirp)
+        }
+    case resolveSuspendAuthor:
+        var duration int32
+        duration, err = suspensionSeconds(res.SuspendDays)
+        if err != nil {
+            respondWithError(w, http.StatusBadRequest, err.Error(), err)
+            return
+        }
+        _, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+            DurationSeconds:  duration,
+            SuspensionReason: sql.NullString{String: res.Note, Valid: true},
+            ID:               report.ChirpAuthorID,
+        })
+    default:
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown action '%s'", res.Action), nil)
+        return
+    }
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusConflict, "reported chirp or author no longer exists", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error applying moderation action", err)
+        return
+    }
+    resolved, err := qtx.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+        Status:         status,
+        Resolution:     sql.NullString{String: res.Action, Valid: true},
+        ResolutionNote: sql.NullString{String: res.Note, Valid: res.Note != ""},
+        ResolvedBy:     uuid.NullUUID{UUID: moderatorID, Valid: true},
+        ChirpID:        report.ChirpID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error resolving reports", err)
+        return
+    }
+
+    // record who did what
+    err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+        ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+        Action:       res.Action,
+        ChirpID:      uuid.NullUUID{UUID: report.ChirpID, Valid: true},
+        TargetUserID: uuid.NullUUID{UUID: report.ChirpAuthorID, Valid: true},
+        ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+        Note:         res.Note,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error recording moderation action", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing resolution", err)
+        return
+    }
+
+    items := make([]ModeratorReport, 0, len(resolved))
+    for _, item := range resolved {
+        items = append(items, moderatorReportFromDB(item))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerGetHeldChirps(w http.ResponseWriter, r *http.Request) {
+    moderatorID, ok := cfg.authenticateModerator(w, r)
+    if !ok {
+        return
+    }
+
+    // chirps held by the moderation pipeline, oldest first
+    chirps, err := cfg.dbQueries.GetChirpsByModerationState(r.Context(), chirpStateHeld)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting held chirps", err)
+        return
+    }
+    items, err := cfg.recastChirps(r.Context(), uuid.NullUUID{UUID: moderatorID, Valid: true}, chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    byID := make(map[uuid.UUID]database.Chirp, len(chirps))
+    for _, chirp := range chirps {
+        byID[chirp.ID] = chirp
+    }
+    for i := range items {
+        chirp := byID[items[i].ID]
+        items[i].Moderation = &ChirpModeration{State: chirp.ModerationState, Rules: chirp.ModerationRules}
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerReviewChirp(w http.ResponseWriter, r *http.Request) {
+    moderatorID, ok := cfg.authenticateModerator(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // approving makes the chirp visible, hiding takes it out of every list
+    action := r.PathValue("action")
+    state := map[string]string{"approve": chirpStateVisible, "hide": chirpStateHidden}[action]
+    if state == "" {
+        respondWithError(w, http.StatusNotFound, fmt.Sprintf("unknown review action '%s'", action), nil)
+        return
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    before, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp", err)
+        return
+    }
+    chirp, err := qtx.SetChirpModerationState(r.Context(), database.SetChirpModerationStateParams{
+        ID:              chirpID,
+        ModerationState: state,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating chirp", err)
+        return
+    }
+    err = announceModeration(r.Context(), qtx, before, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error announcing chirp", err)
+        return
+    }
+    err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+        ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+        Action:       action,
+        ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+        TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error recording moderation action", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing review", err)
+        return
+    }
+
+    // an approved chirp is published now, so notify as when it was created
+    if before.ModerationState != chirpStateVisible && chirp.ModerationState == chirpStateVisible {
+        cfg.notifyNewChirp(r.Context(), chirp)
+    }
+
+    items, err := cfg.recastChirps(r.Context(), uuid.NullUUID{UUID: moderatorID, Valid: true}, []database.Chirp{chirp})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    item := items[0]
+    item.Moderation = &ChirpModeration{State: chirp.ModerationState, Rules: chirp.ModerationRules}
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+// role struct to unmarshal PUT requests
+type InitRole struct {
+    Role  string  `json:"role"`
+}
+
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+    // only admins give out roles
+    adminID, ok := cfg.authenticateAdmin(w, r)
+    if !ok {
+        return
+    }
+    userID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return
+    }
+
+    // decode request body and check the role
+    decoder := json.NewDecoder(r.Body)
+    role := &InitRole{}
+    err := decoder.Decode(role)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if role.Role != roleUser && role.Role != roleModerator && role.Role != roleAdmin {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown role '%s'", role.Role), nil)
+        return
+    }
+    // an admin stepping down could leave no one to give the role back
+    if userID == adminID && role.Role != roleAdmin {
+        respondWithError(w, http.StatusBadRequest, "admins cannot remove their own admin role", nil)
+        return
+    }
+
+    user, err := cfg.dbQueries.SetUserRole(r.Context(), database.SetUserRoleParams{
+        ID:   userID,
+        Role: role.Role,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return
+    }
+    user.HashedPassword = ""
+    respondWithJSON(w, http.StatusOK, userFromDB(user))
+    return
+}
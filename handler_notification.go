@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+    ID          uuid.UUID        `json:"id"`
+    Type        string           `json:"type"`
+    CreatedAt   time.Time        `json:"created_at"`
+    UpdatedAt   time.Time        `json:"updated_at"`
+    Read        bool             `json:"read"`
+    Summary     string           `json:"summary"`
+    Actors      []AuthorSummary  `json:"actors"`
+    ActorCount  int32            `json:"actor_count"`
+    Chirp       *Chirp           `json:"chirp,omitempty"`
+}
+
+type NotificationPage struct {
+    Notifications  []Notification  `json:"notifications"`
+    UnreadCount    int64           `json:"unread_count"`
+    NextCursor     string          `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    notifications, err := cfg.dbQueries.GetNotifications(r.Context(), database.GetNotificationsParams{
+        UserID:          userID,
+        BeforeUpdatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting notifications", err)
+        return
+    }
+    unread, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error counting notifications", err)
+        return
+    }
+    items, err := cfg.recastNotifications(r.Context(), userID, notifications)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting notification data", err)
+        return
+    }
+
+    page := NotificationPage{Notifications: items, UnreadCount: unread}
+    if len(notifications) == int(limit) {
+        last := notifications[len(notifications)-1]
+        page.NextCursor = encodeCursor(last.UpdatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
+
+// recast notifications with their actors and chirps, loading each in one batch
+func (cfg *apiConfig) recastNotifications(ctx context.Context, userID uuid.UUID, notifications []database.Notification) ([]Notification, error) {
+    actorIDs := []uuid.UUID{}
+    chirpIDs := []uuid.UUID{}
+    for _, n := range notifications {
+        actorIDs = append(actorIDs, n.ActorIds...)
+        if n.ChirpID.Valid {
+            chirpIDs = append(chirpIDs, n.ChirpID.UUID)
+        }
+    }
+    users, err := cfg.dbQueries.GetUsersByIDs(ctx, actorIDs)
+    if err != nil {
+        return nil, err
+    }
+    actors := make(map[uuid.UUID]AuthorSummary, len(users))
+    for _, user := range users {
+        actors[user.ID] = authorFromDB(user)
+    }
+    chirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+    if err != nil {
+        return nil, err
+    }
+    hydrated, err := cfg.hydrateChirps(ctx, uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+    if err != nil {
+        return nil, err
+    }
+    byID := make(map[uuid.UUID]*Chirp, len(hydrated))
+    for i := range hydrated {
+        byID[hydrated[i].ID] = &hydrated[i]
+    }
+
+    items := make([]Notification, 0, len(notifications))
+    for _, n := range notifications {
+        item := Notification{
+            ID:         n.ID,
+            Type:       n.Type,
+            CreatedAt:  n.CreatedAt,
+            UpdatedAt:  n.UpdatedAt,
+            Read:       n.ReadAt.Valid,
+            Actors:     []AuthorSummary{},
+            ActorCount: n.ActorCount,
+        }
+        for _, id := range n.ActorIds {
+            if actor, ok := actors[id]; ok {
+                item.Actors = append(item.Actors, actor)
+            }
+        }
+        if n.ChirpID.Valid {
+            item.Chirp = byID[n.ChirpID.UUID]
+        }
+        item.Summary = notificationSummary(n.Type, item.Actors, n.ActorCount)
+        items = append(items, item)
+    }
+    return items, nil
+}
+
+func (cfg *apiConfig) handlerReadNotification(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    notificationID, ok := parsePathUUID(w, r, "notification_id")
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+        ID:     notificationID,
+        UserID: userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error marking notification read", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerReadAllNotifications(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    _, err := cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error marking notifications read", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+// every notification type with whether it is turned on
+func (cfg *apiConfig) notificationPreferences(r *http.Request, userID uuid.UUID) (map[string]bool, error) {
+    prefs, err := cfg.dbQueries.GetNotificationPreferences(r.Context(), userID)
+    if err != nil {
+        return nil, err
+    }
+    items := make(map[string]bool, len(notificationTypes))
+    for _, kind := range notificationTypes {
+        items[kind] = true
+    }
+    for _, pref := range prefs {
+        items[pref.Type] = pref.Enabled
+    }
+    return items, nil
+}
+
+func (cfg *apiConfig) handlerGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    items, err := cfg.notificationPreferences(r, userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting notification preferences", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+// update the given notification types, keeping the rest as they are
+func (cfg *apiConfig) handlerUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    prefs := map[string]bool{}
+    err := decoder.Decode(&prefs)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    fields := map[string]string{}
+    for kind := range prefs {
+        if !slices.Contains(notificationTypes, kind) {
+            fields[kind] = "unknown notification type"
+        }
+    }
+    if len(fields) > 0 {
+        respondWithJSON(w, http.StatusUnprocessableEntity, FieldErrors{Error: "invalid fields", Fields: fields})
+        return
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    for kind, enabled := range prefs {
+        err = qtx.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+            UserID:  userID,
+            Type:    kind,
+            Enabled: enabled,
+        })
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error updating notification preferences", err)
+            return
+        }
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing notification preferences", err)
+        return
+    }
+
+    items, err := cfg.notificationPreferences(r, userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting notification preferences", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
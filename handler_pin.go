@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// how many chirps an author can pin to their profile
+const (
+    maxPinnedChirps     = 1
+    maxPinnedChirpsRed  = 3
+)
+
+func (cfg *apiConfig) handlerPinChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // authors can pin their own chirps, but not their rechirps
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    if chirp.UserID != userID {
+        respondWithError(w, http.StatusForbidden, "action forbidden: incorrect user_id", nil)
+        return
+    }
+    if chirp.RechirpOf.Valid {
+        respondWithError(w, http.StatusBadRequest, "rechirps cannot be pinned", nil)
+        return
+    }
+    if chirp.ModerationState != chirpStateVisible {
+        respondWithError(w, http.StatusConflict, "chirp is held for moderation", nil)
+        return
+    }
+
+    // lock the user while pinning, so concurrent pins are counted one after
+    // the other
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    user, err := qtx.GetUserForUpdate(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting user", err)
+        return
+    }
+
+    // Chirpy Red users can pin more
+    maxPins := maxPinnedChirps
+    if user.IsChirpyRed {
+        maxPins = maxPinnedChirpsRed
+    }
+    n, err := qtx.PinChirp(r.Context(), database.PinChirpParams{
+        ChirpID: chirpID,
+        UserID:  userID,
+        MaxPins: int32(maxPins),
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error pinning chirp", err)
+        return
+    }
+    if n == 0 {
+        // either it was already pinned or the user is at their limit
+        count, err := qtx.CountPinnedChirps(r.Context(), userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error counting pinned chirps", err)
+            return
+        }
+        if count >= int64(maxPins) {
+            respondWithError(w, http.StatusConflict, "pinned chirp limit reached", nil)
+            return
+        }
+        respondWithError(w, http.StatusConflict, "chirp already pinned", nil)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing pin", err)
+        return
+    }
+
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    item.Pinned = true
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerUnpinChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    n, err := cfg.dbQueries.UnpinChirp(r.Context(), database.UnpinChirpParams{
+        ChirpID: chirpID,
+        UserID:  userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error unpinning chirp", err)
+        return
+    }
+    if n == 0 {
+        respondWithError(w, http.StatusNotFound, "pinned chirp not found", nil)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+// put an author's pinned chirps ahead of the rest of their chirps, without
+// repeating them
+func (cfg *apiConfig) withPinnedChirps(ctx context.Context, viewer uuid.NullUUID, authorID uuid.UUID, items []Chirp) ([]Chirp, error) {
+    pinned, err := cfg.dbQueries.GetPinnedChirpsByUser(ctx, authorID)
+    if err != nil || len(pinned) == 0 {
+        return items, err
+    }
+    pinnedItems, err := cfg.listChirps(ctx, viewer, pinned)
+    if err != nil {
+        return nil, err
+    }
+    pinnedIDs := make(map[uuid.UUID]struct{}, len(pinnedItems))
+    for i := range pinnedItems {
+        pinnedItems[i].Pinned = true
+        pinnedIDs[pinnedItems[i].ID] = struct{}{}
+    }
+    for _, item := range items {
+        if _, ok := pinnedIDs[item.ID]; !ok {
+            pinnedItems = append(pinnedItems, item)
+        }
+    }
+    return pinnedItems, nil
+}
@@ -51,6 +51,11 @@ func (cfg *apiConfig) handlerUpgradeUserToRed(w http.ResponseWriter, r *http.Req
         respondWithError(w, http.StatusNotFound, "unable to find user", err)
         return
     }
+    err = notify(r.Context(), cfg.dbQueries, data.UserID, notifyRedUpgrade, uuid.NullUUID{})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error notifying user", err)
+        return
+    }
 
     // do not respond with data
     respondWithJSON(w, http.StatusNoContent, nil)
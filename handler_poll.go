@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// vote on a chirp's poll, by option position
+type InitVote struct {
+    Option  int32  `json:"option"`
+}
+
+func (cfg *apiConfig) handlerVotePoll(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    vote := InitVote{}
+    err := decoder.Decode(&vote)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+
+    // find the chirp, voting on the original of a rechirp
+    viewer := uuid.NullUUID{UUID: userID, Valid: true}
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err == nil && chirp.RechirpOf.Valid {
+        chirpID = chirp.RechirpOf.UUID
+        chirp, err = cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    }
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    ok, err = cfg.canViewChirp(r.Context(), viewer, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // check the poll is still open and has the option
+    poll, err := cfg.dbQueries.GetPoll(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp has no poll", err)
+        return
+    }
+    if !time.Now().Before(poll.ClosesAt) {
+        respondWithError(w, http.StatusConflict, "poll is closed", nil)
+        return
+    }
+    options, err := cfg.dbQueries.GetPollOptionsForChirps(r.Context(), []uuid.UUID{chirpID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting poll options", err)
+        return
+    }
+    if vote.Option < 1 || int(vote.Option) > len(options) {
+        respondWithError(w, http.StatusBadRequest, "unknown poll option", nil)
+        return
+    }
+
+    // the insert itself only counts votes on open polls, once per user
+    n, err := cfg.dbQueries.VotePoll(r.Context(), database.VotePollParams{
+        UserID:   userID,
+        Position: vote.Option,
+        ChirpID:  chirpID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error recording vote", err)
+        return
+    }
+    if n == 0 {
+        respondWithError(w, http.StatusConflict, "already voted or poll is closed", nil)
+        return
+    }
+
+    // respond with the chirp, now showing the tallies
+    item, err := cfg.hydrateChirp(r.Context(), viewer, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, item)
+    return
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// profile fields the user can set
+type InitProfile struct {
+    Handle         string         `json:"handle"`
+    DisplayName    string         `json:"display_name"`
+    Bio            string         `json:"bio"`
+    AvatarMediaID  uuid.NullUUID  `json:"avatar_media_id"`
+}
+
+// public profile, which never includes the email or password
+type Profile struct {
+    ID              uuid.UUID  `json:"id"`
+    CreatedAt       time.Time  `json:"created_at"`
+    Handle          string     `json:"handle"`
+    DisplayName     string     `json:"display_name"`
+    Bio             string     `json:"bio"`
+    AvatarURL       string     `json:"avatar_url,omitempty"`
+    IsChirpyRed     bool       `json:"is_chirpy_red"`
+    ChirpCount      int64      `json:"chirp_count"`
+    FollowerCount   int64      `json:"follower_count"`
+    FollowingCount  int64      `json:"following_count"`
+    PinnedChirps    []Chirp    `json:"pinned_chirps"`
+}
+
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, r *http.Request) {
+    user, err := cfg.lookupUser(r.Context(), r.PathValue("handle_or_id"))
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return
+    }
+
+    counts, err := cfg.dbQueries.GetUserProfileCounts(r.Context(), user.ID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error counting profile activity", err)
+        return
+    }
+    pinned, err := cfg.withPinnedChirps(r.Context(), cfg.viewerID(r), user.ID, []Chirp{})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting pinned chirps", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, Profile{
+        ID:             user.ID,
+        CreatedAt:      user.CreatedAt,
+        Handle:         user.Handle,
+        DisplayName:    user.DisplayName,
+        Bio:            user.Bio,
+        AvatarURL:      avatarURL(user),
+        IsChirpyRed:    user.IsChirpyRed,
+        ChirpCount:     counts.ChirpCount,
+        FollowerCount:  counts.FollowerCount,
+        FollowingCount: counts.FollowingCount,
+        PinnedChirps:   pinned,
+    })
+    return
+}
+
+func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    decoder := json.NewDecoder(r.Body)
+    p := InitProfile{}
+    err := decoder.Decode(&p)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+
+    // validate the profile fields
+    if err = validateHandle(p.Handle); err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid handle", err)
+        return
+    }
+    p.DisplayName, err = normalizeProfileText("display name", p.DisplayName, maxDisplayNameLength)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid display name", err)
+        return
+    }
+    p.Bio, err = normalizeProfileText("bio", p.Bio, maxBioLength)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid bio", err)
+        return
+    }
+    if !cfg.checkAvatar(w, r, userID, p.AvatarMediaID) {
+        return
+    }
+
+    user, err := cfg.dbQueries.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+        ID:            userID,
+        Handle:        p.Handle,
+        DisplayName:   p.DisplayName,
+        Bio:           p.Bio,
+        AvatarMediaID: p.AvatarMediaID,
+    })
+    if isUniqueViolation(err) {
+        respondWithError(w, http.StatusConflict, "handle already taken", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating profile", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, userFromDB(user))
+    return
+}
+
+// avatars must be one of the user's own uploads
+func (cfg *apiConfig) checkAvatar(w http.ResponseWriter, r *http.Request, userID uuid.UUID, mediaID uuid.NullUUID) bool {
+    if !mediaID.Valid {
+        return true
+    }
+    attachment, err := cfg.dbQueries.GetMediaAttachment(r.Context(), mediaID.UUID)
+    if errors.Is(err, sql.ErrNoRows) || (err == nil && attachment.UserID != userID) {
+        respondWithError(w, http.StatusNotFound, "avatar media not found", err)
+        return false
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting avatar media", err)
+        return false
+    }
+    return true
+}
+
+// whether the error is a unique constraint violation, like a taken handle, on
+// one of the given constraints or on any when none are given
+func isUniqueViolation(err error, constraints ...string) bool {
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+        return false
+    }
+    return len(constraints) == 0 || slices.Contains(constraints, pqErr.Constraint)
+}
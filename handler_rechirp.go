@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    original, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+
+    // rechirping a rechirp shares the original
+    if original.RechirpOf.Valid {
+        chirpID = original.RechirpOf.UUID
+        original, err = cfg.dbQueries.GetChirp(r.Context(), chirpID)
+        if err != nil {
+            respondWithError(w, http.StatusNotFound, "chirp not found", err)
+            return
+        }
+    }
+    ok, err = cfg.canViewChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, original)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // rechirps are public, so only public and unlisted chirps can be shared
+    if restrictedVisibility(original.Visibility) {
+        respondWithError(w, http.StatusForbidden, "only public chirps can be rechirped", nil)
+        return
+    }
+
+    // create the rechirp and bump the counter in one transaction
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    rechirp, err := qtx.CreateRechirp(r.Context(), database.CreateRechirpParams{
+        UserID:    userID,
+        RechirpOf: uuid.NullUUID{UUID: chirpID, Valid: true},
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusConflict, "chirp already rechirped", nil)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating rechirp", err)
+        return
+    }
+    err = qtx.IncrementChirpRechirps(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating rechirp count", err)
+        return
+    }
+    err = notify(r.Context(), qtx, original.UserID, notifyRechirp, uuid.NullUUID{UUID: chirpID, Valid: true}, userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error notifying author", err)
+        return
+    }
+    // followers see the rechirp arrive in their live home timelines
+    err = publishEvent(r.Context(), qtx, eventChirpCreated, chirpEventData(rechirp))
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error publishing rechirp", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing rechirp", err)
+        return
+    }
+
+    // respond with the rechirp embedding its original
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, rechirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, item)
+    return
+}
+
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    original, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    if original.RechirpOf.Valid {
+        chirpID = original.RechirpOf.UUID
+    }
+
+    // remove the rechirp and drop the counter in one transaction
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    rechirp, err := qtx.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+        UserID:    userID,
+        RechirpOf: uuid.NullUUID{UUID: chirpID, Valid: true},
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "rechirp not found", nil)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting rechirp", err)
+        return
+    }
+    err = qtx.DecrementChirpRechirps(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating rechirp count", err)
+        return
+    }
+    err = publishEvent(r.Context(), qtx, eventChirpDeleted, chirpEventData(rechirp))
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error publishing rechirp deletion", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing rechirp", err)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
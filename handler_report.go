@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// reasons a chirp can be reported for
+var reportReasons = map[string]struct{}{
+    "spam":            {},
+    "harassment":      {},
+    "hate":            {},
+    "violence":        {},
+    "self_harm":       {},
+    "misinformation":  {},
+    "other":           {},
+}
+
+// report struct to unmarshal POST requests
+type InitReport struct {
+    Reason   string  `json:"reason"`
+    Details  string  `json:"details"`
+}
+
+// report as seen by the reporter
+type Report struct {
+    ID          uuid.UUID   `json:"id"`
+    CreatedAt   time.Time   `json:"created_at"`
+    UpdatedAt   time.Time   `json:"updated_at"`
+    ChirpID     uuid.UUID   `json:"chirp_id"`
+    Reason      string      `json:"reason"`
+    Details     string      `json:"details"`
+    Status      string      `json:"status"`
+    Resolution  string      `json:"resolution,omitempty"`
+    ResolvedAt  *time.Time  `json:"resolved_at,omitempty"`
+}
+
+func reportFromDB(report database.Report) Report {
+    item := Report{
+        ID:         report.ID,
+        CreatedAt:  report.CreatedAt,
+        UpdatedAt:  report.UpdatedAt,
+        ChirpID:    report.ChirpID,
+        Reason:     report.Reason,
+        Details:    report.Details,
+        Status:     report.Status,
+        Resolution: report.Resolution.String,
+    }
+    if report.ResolvedAt.Valid {
+        item.ResolvedAt = &report.ResolvedAt.Time
+    }
+    return item
+}
+
+func (cfg *apiConfig) handlerCreateReport(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // decode request body and check the reason code
+    decoder := json.NewDecoder(r.Body)
+    rep := &InitReport{}
+    err := decoder.Decode(rep)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if _, ok := reportReasons[rep.Reason]; !ok {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown report reason '%s'", rep.Reason), nil)
+        return
+    }
+
+    // reports on a rechirp are about the original
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err == nil && chirp.RechirpOf.Valid {
+        chirp, err = cfg.dbQueries.GetChirp(r.Context(), chirp.RechirpOf.UUID)
+    }
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    ok, err = cfg.canViewChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // keep a copy of the chirp with the report
+    report, err := cfg.dbQueries.CreateReport(r.Context(), database.CreateReportParams{
+        ReporterID:    userID,
+        ChirpID:       chirp.ID,
+        ChirpAuthorID: chirp.UserID,
+        ChirpBody:     chirp.Body,
+        Reason:        rep.Reason,
+        Details:       rep.Details,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusConflict, "chirp already reported", nil)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating report", err)
+        return
+    }
+    respondWithJSON(w, http.StatusCreated, reportFromDB(report))
+    return
+}
+
+func (cfg *apiConfig) handlerGetMyReports(w http.ResponseWriter, r *http.Request) {
+    // authenticate user, suspended users can still follow their reports
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+
+    // list the user's reports with their status, most recent first
+    reports, err := cfg.dbQueries.GetReportsByReporter(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting reports", err)
+        return
+    }
+    items := make([]Report, 0, len(reports))
+    for _, report := range reports {
+        items = append(items, reportFromDB(report))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
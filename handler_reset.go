@@ -13,6 +13,11 @@ func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
     cfg.fileserverHits.Store(0)
     w.WriteHeader(200)
     w.Write([]byte("Hits successfully reset!"))
+    // private messages outlive their senders, so they are only reset when
+    // asked for
+    if r.URL.Query().Get("include") == "messages" {
+        cfg.dbQueries.ResetConversations(r.Context())
+    }
     // reset users
     cfg.dbQueries.ResetUsers(r.Context())
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // restore the chirp and count it on its original again
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    chirp, err := qtx.RestoreChirp(r.Context(), database.RestoreChirpParams{
+        ID:            chirpID,
+        UserID:        userID,
+        WindowSeconds: int32(cfg.restoreWindow.Seconds()),
+    })
+    var pqErr *pq.Error
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "no deleted chirp to restore within the restore window", err)
+        return
+    } else if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        respondWithError(w, http.StatusConflict, "chirp was rechirped again since it was deleted", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error restoring chirp", err)
+        return
+    }
+    if chirp.RechirpOf.Valid {
+        err = qtx.IncrementChirpRechirps(r.Context(), chirp.RechirpOf.UUID)
+    } else if chirp.QuoteOf.Valid {
+        err = qtx.IncrementChirpQuotes(r.Context(), chirp.QuoteOf.UUID)
+    }
+    if err == nil && chirp.ReplyTo.Valid {
+        err = qtx.IncrementChirpReplies(r.Context(), chirp.ReplyTo.UUID)
+    }
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating counts", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing restore", err)
+        return
+    }
+
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerGetDeletedChirps(w http.ResponseWriter, r *http.Request) {
+    adminID, ok := cfg.authenticateAdmin(w, r)
+    if !ok {
+        return
+    }
+
+    // most recently deleted first, until they are purged
+    chirps, err := cfg.dbQueries.GetDeletedChirps(r.Context())
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting deleted chirps", err)
+        return
+    }
+    items, err := cfg.recastChirps(r.Context(), uuid.NullUUID{UUID: adminID, Valid: true}, chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+func (cfg *apiConfig) handlerAdminGetChirp(w http.ResponseWriter, r *http.Request) {
+    adminID, ok := cfg.authenticateAdmin(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // any chirp that has not been purged, with its moderation state
+    chirp, err := cfg.dbQueries.GetChirpIncludingDeleted(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    items, err := cfg.recastChirps(r.Context(), uuid.NullUUID{UUID: adminID, Valid: true}, []database.Chirp{chirp})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    items[0].Moderation = &ChirpModeration{State: chirp.ModerationState, Rules: chirp.ModerationRules}
+    respondWithJSON(w, http.StatusOK, items[0])
+    return
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// previous or current body of a chirp
+type ChirpRevision struct {
+    Body       string     `json:"body"`
+    CreatedAt  time.Time  `json:"created_at"`
+    Current    bool       `json:"current"`
+}
+
+func (cfg *apiConfig) handlerEditChirp(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+
+    // decode request body and run the same checks as creation
+    decoder := json.NewDecoder(r.Body)
+    chp := &InitChirp{}
+    err := decoder.Decode(chp)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    maxLength, err := cfg.chirpLengthLimit(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting user", err)
+        return
+    }
+    moderated, err := cfg.validateChirpBody(chp.Body, maxLength)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "invalid chirp", err)
+        return
+    }
+
+    // lock the chirp so concurrent edits each keep their revision
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    if chirp.UserID != userID {
+        respondWithError(w, http.StatusForbidden, "action forbidden: incorrect user_id", nil)
+        return
+    }
+    if chirp.RechirpOf.Valid {
+        respondWithError(w, http.StatusBadRequest, "rechirps cannot be edited", nil)
+        return
+    }
+
+    // keep the old body, then replace it if still inside the edit window
+    err = qtx.CreateChirpRevision(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error saving chirp revision", err)
+        return
+    }
+    // the chirp is locked, so its state cannot change before the update
+    chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+        Body:            moderated.Body,
+        ModerationState: moderated.EditedState(chirp.ModerationState),
+        ModerationRules: moderated.Rules,
+        ID:              chirpID,
+        WindowSeconds:   int32(cfg.editWindow.Seconds()),
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusForbidden, "edit window has passed", nil)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error editing chirp", err)
+        return
+    }
+
+    // re-extract hashtags and mentions from the new body
+    err = qtx.DeleteChirpHashtags(r.Context(), chirpID)
+    if err == nil {
+        err = qtx.DeleteChirpMentions(r.Context(), chirpID)
+    }
+    if err == nil {
+        err = cfg.saveChirpEntities(r.Context(), qtx, chirp)
+    }
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error saving hashtags and mentions", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing edit", err)
+        return
+    }
+
+    item, err := cfg.hydrateChirp(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, item)
+    return
+}
+
+func (cfg *apiConfig) handlerGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "chirp not found", err)
+        return
+    }
+    ok, err = cfg.canViewChirp(r.Context(), cfg.viewerID(r), chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "chirp not found", nil)
+        return
+    }
+
+    // list the revisions oldest first, ending with the current body
+    revisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp history", err)
+        return
+    }
+    items := make([]ChirpRevision, 0, len(revisions)+1)
+    for _, revision := range revisions {
+        items = append(items, ChirpRevision{Body: revision.Body, CreatedAt: revision.CreatedAt})
+    }
+    items = append(items, ChirpRevision{Body: chirp.Body, CreatedAt: chirp.UpdatedAt, Current: true})
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
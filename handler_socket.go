@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/events"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+    socketWriteWait     = 10 * time.Second
+    socketPongWait      = 60 * time.Second
+    socketPingPeriod    = 50 * time.Second
+    socketCloseWait     = time.Second
+    socketReadLimit     = 4096
+    // events sent but not acknowledged before a client counts as too slow
+    socketMaxUnacked    = 256
+    socketMaxChannels   = 50
+    // close code for clients that fall behind, who should reconnect and
+    // resume from their last acknowledged event
+    socketCloseTooSlow  = 4008
+)
+
+var socketUpgrader = websocket.Upgrader{
+    ReadBufferSize:   1024,
+    WriteBufferSize:  4096,
+}
+
+// message from the client. Subscribe and unsubscribe are answered with an
+// "ok" or "error" message carrying the same ID, while acks acknowledge an
+// event and every event before it.
+type SocketCommand struct {
+    Type     string  `json:"type"`
+    ID       string  `json:"id,omitempty"`
+    Channel  string  `json:"channel,omitempty"`
+    EventID  string  `json:"event_id,omitempty"`
+}
+
+// message to the client: an "event" on one or more channels, a "reset" when
+// events may have been missed, or the answer to a command
+type SocketMessage struct {
+    Type      string    `json:"type"`
+    ID        string    `json:"id,omitempty"`
+    EventID   string    `json:"event_id,omitempty"`
+    Event     string    `json:"event,omitempty"`
+    Channels  []string  `json:"channels,omitempty"`
+    Data      any       `json:"data,omitempty"`
+    Error     string    `json:"error,omitempty"`
+}
+
+// a subscription, named "home", "notifications", "messages",
+// "hashtag:<tag>", "user:<handle or id>" or "thread:<chirp id>". A thread is
+// the first chirp of the conversation the given chirp belongs to and every
+// reply under it.
+type socketChannel struct {
+    kind  string
+    tag   string
+    id    uuid.UUID
+}
+
+// state of one connection, owned by the goroutine writing to it
+type socketConn struct {
+    cfg        *apiConfig
+    ws         *websocket.Conn
+    userID     uuid.UUID
+    followees  map[uuid.UUID]bool
+    channels   map[string]socketChannel
+    unacked    []string
+}
+
+func (cfg *apiConfig) handlerSocket(w http.ResponseWriter, r *http.Request) {
+    // authenticate user before upgrading, so errors are plain responses
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    if !cfg.live.add() {
+        respondWithError(w, http.StatusServiceUnavailable, "server shutting down", nil)
+        return
+    }
+    defer cfg.live.done()
+
+    conn := &socketConn{cfg: cfg, userID: userID, channels: map[string]socketChannel{}}
+    followees, err := cfg.followeeSet(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting follows", err)
+        return
+    }
+    conn.followees = followees
+
+    // channels can be given up front, so events missed while reconnecting
+    // are replayed to them
+    for _, name := range strings.Split(r.URL.Query().Get("channels"), ",") {
+        if name == "" {
+            continue
+        }
+        if err = conn.subscribe(r.Context(), name); err != nil {
+            respondWithError(w, http.StatusBadRequest, err.Error(), nil)
+            return
+        }
+    }
+
+    ws, err := socketUpgrader.Upgrade(w, r, nil)
+    if err != nil {
+        // the upgrader has already responded
+        return
+    }
+    defer ws.Close()
+    conn.ws = ws
+    sub, missed, resumed := cfg.events.Subscribe(r.URL.Query().Get("last_event_id"))
+    defer cfg.events.Unsubscribe(sub)
+    conn.serve(context.WithoutCancel(r.Context()), sub, missed, resumed)
+    return
+}
+
+// read commands on their own goroutine, since a connection has one reader
+// and one writer, until the connection fails or done is closed
+func (c *socketConn) readCommands(commands chan<- SocketCommand, done <-chan struct{}) {
+    defer close(commands)
+    c.ws.SetReadLimit(socketReadLimit)
+    c.ws.SetReadDeadline(time.Now().Add(socketPongWait))
+    c.ws.SetPongHandler(func(string) error {
+        return c.ws.SetReadDeadline(time.Now().Add(socketPongWait))
+    })
+    for {
+        command := SocketCommand{}
+        if err := c.ws.ReadJSON(&command); err != nil {
+            if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+                log.Printf("error reading from socket of %s: %s", c.userID, err)
+            }
+            return
+        }
+        select {
+        case commands <- command:
+        case <-done:
+            return
+        }
+    }
+}
+
+func (c *socketConn) serve(ctx context.Context, sub *events.Subscription, missed []events.Event, resumed bool) {
+    commands := make(chan SocketCommand, 16)
+    done := make(chan struct{})
+    defer close(done)
+    go c.readCommands(commands, done)
+
+    if !resumed {
+        missed = []events.Event{{Type: events.TypeReset}}
+    }
+    for _, event := range missed {
+        if err := c.sendEvent(ctx, event); err != nil {
+            return
+        }
+    }
+
+    ping := time.NewTicker(socketPingPeriod)
+    defer ping.Stop()
+    for {
+        var err error
+        select {
+        case <-c.cfg.live.draining:
+            // tell the client to reconnect elsewhere, and give it a moment
+            // to close its end
+            c.close(websocket.CloseGoingAway, "server shutting down")
+            select {
+            case <-commands:
+            case <-time.After(socketCloseWait):
+            }
+            return
+        case command, ok := <-commands:
+            if !ok {
+                return
+            }
+            err = c.handleCommand(ctx, command)
+        case event, ok := <-sub.C:
+            if !ok {
+                c.close(socketCloseTooSlow, "too slow")
+                return
+            }
+            err = c.sendEvent(ctx, event)
+        case <-ping.C:
+            err = c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait))
+            // pick up follows made since the connection opened
+            if followees, err := c.cfg.followeeSet(ctx, c.userID); err == nil {
+                c.followees = followees
+            }
+        }
+        if err != nil {
+            return
+        }
+        if len(c.unacked) > socketMaxUnacked {
+            c.close(socketCloseTooSlow, "too many unacknowledged events")
+            return
+        }
+    }
+}
+
+func (c *socketConn) handleCommand(ctx context.Context, command SocketCommand) error {
+    var err error
+    switch command.Type {
+    case "subscribe":
+        err = c.subscribe(ctx, command.Channel)
+    case "unsubscribe":
+        delete(c.channels, command.Channel)
+    case "ack":
+        // acknowledge the event and everything sent before it
+        if i := slices.Index(c.unacked, command.EventID); i >= 0 {
+            c.unacked = c.unacked[i+1:]
+        }
+        return nil
+    default:
+        err = fmt.Errorf("unknown command '%s'", command.Type)
+    }
+    if err != nil {
+        return c.write(SocketMessage{Type: "error", ID: command.ID, Error: err.Error()})
+    }
+    return c.write(SocketMessage{Type: "ok", ID: command.ID})
+}
+
+// parse and check a channel name before subscribing to it
+func (c *socketConn) subscribe(ctx context.Context, name string) error {
+    if _, ok := c.channels[name]; ok {
+        return nil
+    }
+    if len(c.channels) >= socketMaxChannels {
+        return fmt.Errorf("at most %d channels can be subscribed to", socketMaxChannels)
+    }
+    kind, arg, _ := strings.Cut(name, ":")
+    channel := socketChannel{kind: kind}
+    switch kind {
+    case "home", "notifications", "messages":
+        if arg != "" {
+            return fmt.Errorf("unknown channel '%s'", name)
+        }
+    case "hashtag":
+        channel.tag = strings.ToLower(strings.TrimPrefix(arg, "#"))
+        if channel.tag == "" {
+            return errors.New("missing hashtag")
+        }
+    case "user":
+        user, err := c.cfg.lookupUser(ctx, arg)
+        if err != nil {
+            return errors.New("user not found")
+        }
+        channel.id = user.ID
+    case "thread":
+        chirpID, err := uuid.Parse(arg)
+        if err != nil {
+            return errors.New("invalid chirp ID")
+        }
+        chirp, err := c.cfg.dbQueries.GetChirp(ctx, chirpID)
+        if err != nil {
+            return errors.New("chirp not found")
+        }
+        ok, err := c.cfg.canViewChirp(ctx, uuid.NullUUID{UUID: c.userID, Valid: true}, chirp)
+        if err != nil || !ok {
+            return errors.New("chirp not found")
+        }
+        channel.id = chirpID
+        if chirp.ThreadID.Valid {
+            channel.id = chirp.ThreadID.UUID
+        }
+    default:
+        return fmt.Errorf("unknown channel '%s'", name)
+    }
+    c.channels[name] = channel
+    return nil
+}
+
+func (ch socketChannel) matches(c *socketConn, kind string, data eventData) bool {
+    switch kind {
+    case eventNotification:
+        return ch.kind == "notifications" && data.UserID == c.userID
+    case eventMessageCreated:
+        return ch.kind == "messages" && slices.Contains(data.RecipientIDs, c.userID)
+    }
+    if kind != eventChirpCreated && kind != eventChirpDeleted {
+        return false
+    }
+    switch ch.kind {
+    case "home":
+        return c.followees[data.UserID]
+    case "hashtag":
+        return slices.Contains(data.Hashtags, ch.tag)
+    case "user":
+        return data.UserID == ch.id
+    case "thread":
+        return data.ChirpID == ch.id || (data.ThreadID.Valid && data.ThreadID.UUID == ch.id)
+    }
+    return false
+}
+
+// send an event on every channel it matches, if the viewer can see it
+func (c *socketConn) sendEvent(ctx context.Context, event events.Event) error {
+    if event.Type == events.TypeReset {
+        return c.write(SocketMessage{Type: events.TypeReset, EventID: event.ID})
+    }
+    data, err := decodeEventData(event)
+    if err != nil {
+        log.Printf("error decoding %s event %s: %s", event.Type, event.ID, err)
+        return nil
+    }
+    channels := []string{}
+    for name, channel := range c.channels {
+        if channel.matches(c, event.Type, data) {
+            channels = append(channels, name)
+        }
+    }
+    if len(channels) == 0 {
+        return nil
+    }
+    slices.Sort(channels)
+    payload, err := c.cfg.streamPayload(ctx, c.userID, event.Type, data)
+    if err != nil {
+        log.Printf("error preparing %s event %s for %s: %s", event.Type, event.ID, c.userID, err)
+        return nil
+    }
+    if payload == nil {
+        return nil
+    }
+    c.unacked = append(c.unacked, event.ID)
+    return c.write(SocketMessage{Type: "event", EventID: event.ID, Event: event.Type, Channels: channels, Data: payload})
+}
+
+// write a message, giving up on clients that stop reading
+func (c *socketConn) write(msg SocketMessage) error {
+    c.ws.SetWriteDeadline(time.Now().Add(socketWriteWait))
+    return c.ws.WriteJSON(msg)
+}
+
+func (c *socketConn) close(code int, reason string) {
+    msg := websocket.FormatCloseMessage(code, reason)
+    c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(socketWriteWait))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/events"
+	"github.com/google/uuid"
+)
+
+const (
+    // events kept for clients resuming with Last-Event-ID
+    streamReplaySize  = 1000
+    // events a client can fall behind before it is disconnected
+    streamPending     = 64
+    streamHeartbeat   = 15 * time.Second
+)
+
+// an event as sent to one stream, after checking it is for the viewer
+type streamEvent struct {
+    id    string
+    kind  string
+    data  any
+}
+
+type StreamDeletion struct {
+    ID  uuid.UUID  `json:"id"`
+}
+
+type StreamNotification struct {
+    Type         string  `json:"type"`
+    UnreadCount  int64   `json:"unread_count"`
+}
+
+func (cfg *apiConfig) handlerStream(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        respondWithError(w, http.StatusInternalServerError, "streaming unsupported", nil)
+        return
+    }
+    if !cfg.live.add() {
+        respondWithError(w, http.StatusServiceUnavailable, "server shutting down", nil)
+        return
+    }
+    defer cfg.live.done()
+
+    // subscribe before anything else, so nothing is missed in between
+    sub, missed, resumed := cfg.events.Subscribe(r.Header.Get("Last-Event-ID"))
+    defer cfg.events.Unsubscribe(sub)
+    followees, err := cfg.followeeSet(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting follows", err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.Header().Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+
+    // a client resuming from an event that is no longer buffered may have
+    // missed some, so it should refetch
+    if !resumed {
+        missed = []events.Event{{Type: events.TypeReset}}
+    }
+    for _, event := range missed {
+        if err = cfg.sendStreamEvent(r.Context(), w, userID, followees, event); err != nil {
+            return
+        }
+    }
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(streamHeartbeat)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case <-cfg.live.draining:
+            // the client reconnects to another instance and resumes
+            return
+        case <-heartbeat.C:
+            if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+                return
+            }
+            // pick up follows made since the stream started
+            if refreshed, err := cfg.followeeSet(r.Context(), userID); err == nil {
+                followees = refreshed
+            }
+        case event, ok := <-sub.C:
+            // closed when the client fell too far behind, after which it
+            // can reconnect and resume
+            if !ok {
+                return
+            }
+            if err = cfg.sendStreamEvent(r.Context(), w, userID, followees, event); err != nil {
+                return
+            }
+        }
+        flusher.Flush()
+    }
+}
+
+// the users someone follows, including themselves
+func (cfg *apiConfig) followeeSet(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+    ids, err := cfg.dbQueries.GetFolloweeIDs(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    followees := map[uuid.UUID]bool{userID: true}
+    for _, id := range ids {
+        followees[id] = true
+    }
+    return followees, nil
+}
+
+// send an event to a stream if it is for the viewer. Only write errors are
+// returned, since they end the stream; an event that cannot be looked up is
+// logged and skipped.
+func (cfg *apiConfig) sendStreamEvent(ctx context.Context, w io.Writer, userID uuid.UUID, followees map[uuid.UUID]bool, event events.Event) error {
+    item, err := cfg.streamEventFor(ctx, userID, followees, event)
+    if err != nil {
+        log.Printf("error preparing %s event %s for %s: %s", event.Type, event.ID, userID, err)
+        return nil
+    }
+    if item == nil {
+        return nil
+    }
+    return writeStreamEvent(w, *item)
+}
+
+func (cfg *apiConfig) streamEventFor(ctx context.Context, userID uuid.UUID, followees map[uuid.UUID]bool, event events.Event) (*streamEvent, error) {
+    data, err := decodeEventData(event)
+    if err != nil {
+        return nil, err
+    }
+    // chirps from followed users, and the viewer's own notifications and
+    // messages
+    if (event.Type == eventChirpCreated || event.Type == eventChirpDeleted) && !followees[data.UserID] {
+        return nil, nil
+    }
+    payload, err := cfg.streamPayload(ctx, userID, event.Type, data)
+    if err != nil || payload == nil {
+        return nil, err
+    }
+    return &streamEvent{id: event.ID, kind: event.Type, data: payload}, nil
+}
+
+func decodeEventData(event events.Event) (eventData, error) {
+    data := eventData{}
+    if len(event.Data) == 0 {
+        return data, nil
+    }
+    err := json.Unmarshal(event.Data, &data)
+    return data, err
+}
+
+// what a client is sent for an event, or nil if it is not for the viewer.
+// New chirps are sent as the viewer would see them.
+func (cfg *apiConfig) streamPayload(ctx context.Context, userID uuid.UUID, kind string, data eventData) (any, error) {
+    viewer := uuid.NullUUID{UUID: userID, Valid: true}
+    switch kind {
+    case events.TypeReset:
+        return struct{}{}, nil
+    case eventChirpCreated:
+        return cfg.chirpPayload(ctx, viewer, data.ChirpID)
+    case eventChirpDeleted:
+        ok, err := cfg.couldViewChirp(ctx, userID, data)
+        if err != nil || !ok {
+            return nil, err
+        }
+        return StreamDeletion{ID: data.ChirpID}, nil
+    case eventNotification:
+        if data.UserID != userID {
+            return nil, nil
+        }
+        unread, err := cfg.dbQueries.CountUnreadNotifications(ctx, userID)
+        if err != nil {
+            return nil, err
+        }
+        return StreamNotification{Type: data.NotificationType, UnreadCount: unread}, nil
+    case eventMessageCreated:
+        if !slices.Contains(data.RecipientIDs, userID) {
+            return nil, nil
+        }
+        message, err := cfg.dbQueries.GetMessage(ctx, data.MessageID)
+        if err != nil {
+            return nil, err
+        }
+        return messageFromDB(message), nil
+    }
+    return nil, nil
+}
+
+// a new chirp as the viewer sees it, or nil if they cannot, have muted its
+// author or the author of the chirp it rechirps, or it has been deleted since
+func (cfg *apiConfig) chirpPayload(ctx context.Context, viewer uuid.NullUUID, chirpID uuid.UUID) (any, error) {
+    chirp, err := cfg.dbQueries.GetChirp(ctx, chirpID)
+    if err != nil {
+        return nil, nil
+    }
+    ok, err := cfg.canViewChirp(ctx, viewer, chirp)
+    if err != nil || !ok {
+        return nil, err
+    }
+    items, err := cfg.listChirps(ctx, viewer, []database.Chirp{chirp})
+    if err != nil || len(items) == 0 {
+        return nil, err
+    }
+    return items[0], nil
+}
+
+// whether the viewer could see a chirp as it was when the event was
+// published, judged from the event since the chirp may have been deleted
+func (cfg *apiConfig) couldViewChirp(ctx context.Context, userID uuid.UUID, data eventData) (bool, error) {
+    if data.UserID == userID {
+        return true, nil
+    }
+    if data.ModerationState != chirpStateVisible {
+        return false, nil
+    }
+    blocked, err := cfg.isBlocked(ctx, userID, data.UserID)
+    if err != nil || blocked {
+        return false, err
+    }
+    if !restrictedVisibility(data.Visibility) {
+        return true, nil
+    }
+    // soft deleted chirps are still there to check
+    ids, err := cfg.dbQueries.GetViewableChirpIDs(ctx, database.GetViewableChirpIDsParams{
+        Ids:      []uuid.UUID{data.ChirpID},
+        ViewerID: userID,
+    })
+    return len(ids) == 1, err
+}
+
+// write an event in the text/event-stream format
+func writeStreamEvent(w io.Writer, event streamEvent) error {
+    dat, err := json.Marshal(event.data)
+    if err != nil {
+        return err
+    }
+    if event.id != "" {
+        if _, err = fmt.Fprintf(w, "id: %s\n", event.id); err != nil {
+            return err
+        }
+    }
+    _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.kind, dat)
+    return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+    secondsPerDay       = 24 * 60 * 60
+    // ten years, well inside the seconds a suspension is stored in
+    maxSuspensionDays   = 3650
+)
+
+// suspension struct to unmarshal POST requests, a suspension without days is
+// a permanent ban
+type InitSuspension struct {
+    Reason  string  `json:"reason"`
+    Days    int     `json:"days"`
+}
+
+type Suspension struct {
+    Reason          string      `json:"reason"`
+    SuspendedAt     time.Time   `json:"suspended_at"`
+    SuspendedUntil  *time.Time  `json:"suspended_until"`
+    Permanent       bool        `json:"permanent"`
+}
+
+// user with their suspension, as seen by admins
+type SuspendedUser struct {
+    User
+    Suspension  *Suspension  `json:"suspension"`
+}
+
+func (cfg *apiConfig) handlerSuspendUser(w http.ResponseWriter, r *http.Request) {
+    adminID, ok := cfg.authenticateAdmin(w, r)
+    if !ok {
+        return
+    }
+    userID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return
+    }
+
+    // decode request body, a reason is always recorded
+    decoder := json.NewDecoder(r.Body)
+    sus := &InitSuspension{}
+    err := decoder.Decode(sus)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if sus.Reason == "" {
+        respondWithError(w, http.StatusBadRequest, "a reason is required", nil)
+        return
+    }
+    duration, err := suspensionSeconds(sus.Days)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error(), err)
+        return
+    }
+    if userID == adminID {
+        respondWithError(w, http.StatusBadRequest, "admins cannot suspend themselves", nil)
+        return
+    }
+
+    // suspend and log the action together
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    user, err := qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+        DurationSeconds:  duration,
+        SuspensionReason: sql.NullString{String: sus.Reason, Valid: true},
+        ID:               userID,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return
+    }
+    err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+        ModeratorID:  uuid.NullUUID{UUID: adminID, Valid: true},
+        Action:       "suspend",
+        TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+        Note:         sus.Reason,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error recording moderation action", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing suspension", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, suspendedUserFromDB(user))
+    return
+}
+
+// how long a suspension of some days lasts in seconds, with zero for a
+// permanent ban. Days out of range are an error rather than overflowing into
+// a ban or an expired suspension.
+func suspensionSeconds(days int) (int32, error) {
+    if days < 0 || days > maxSuspensionDays {
+        return 0, fmt.Errorf("days must be between 0 and %d", maxSuspensionDays)
+    }
+    return int32(days * secondsPerDay), nil
+}
+
+func (cfg *apiConfig) handlerUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+    adminID, ok := cfg.authenticateAdmin(w, r)
+    if !ok {
+        return
+    }
+    userID, ok := parsePathUUID(w, r, "id")
+    if !ok {
+        return
+    }
+
+    // the reason for lifting a suspension is optional
+    sus := &InitSuspension{}
+    if r.ContentLength > 0 {
+        err := json.NewDecoder(r.Body).Decode(sus)
+        if err != nil {
+            respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+            return
+        }
+    }
+
+    tx, err := cfg.db.BeginTx(r.Context(), nil)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error starting transaction", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    user, err := qtx.UnsuspendUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "user not found", err)
+        return
+    }
+    err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+        ModeratorID:  uuid.NullUUID{UUID: adminID, Valid: true},
+        Action:       "unsuspend",
+        TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+        Note:         sus.Reason,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error recording moderation action", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error committing unsuspension", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, suspendedUserFromDB(user))
+    return
+}
+
+// recast a database user with their suspension, which is null once lifted
+func suspendedUserFromDB(user database.User) SuspendedUser {
+    user.HashedPassword = ""
+    item := SuspendedUser{User: userFromDB(user)}
+    if user.SuspendedAt.Valid {
+        item.Suspension = &Suspension{
+            Reason:      user.SuspensionReason.String,
+            SuspendedAt: user.SuspendedAt.Time,
+            Permanent:   !user.SuspendedUntil.Valid,
+        }
+        if user.SuspendedUntil.Valid {
+            item.Suspension.SuspendedUntil = &user.SuspendedUntil.Time
+        }
+    }
+    return item
+}
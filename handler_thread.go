@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+)
+
+// most chirps shown above a reply in its thread
+const maxThreadAncestors = 50
+
+// a chirp with the chirps it answers, oldest first, and a page of its replies
+type ChirpThread struct {
+    Ancestors   []Chirp  `json:"ancestors"`
+    Chirp       Chirp    `json:"chirp"`
+    Replies     []Chirp  `json:"replies"`
+    NextCursor  string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerGetChirpThread(w http.ResponseWriter, r *http.Request) {
+    chirpID, ok := parsePathUUID(w, r, "chirp_id")
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "error finding chirp", err)
+        return
+    }
+
+    // threads of chirps the viewer cannot see are not found
+    viewer := cfg.viewerID(r)
+    ok, err = cfg.canViewChirp(r.Context(), viewer, chirp)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking chirp visibility", err)
+        return
+    }
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "error finding chirp", nil)
+        return
+    }
+    item, err := cfg.hydrateChirp(r.Context(), viewer, chirp)
+    if errors.Is(err, errChirpUnavailable) {
+        respondWithError(w, http.StatusNotFound, "chirp is unavailable", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+
+    // deleted and held chirps drop out of the thread, other than the viewer's
+    // own held ones, and listing leaves out what the viewer cannot see
+    ancestors, err := cfg.dbQueries.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+        ID:       chirp.ID,
+        MaxDepth: maxThreadAncestors,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting thread", err)
+        return
+    }
+    shown := make([]database.Chirp, 0, len(ancestors))
+    for _, ancestor := range ancestors {
+        if ancestor.DeletedAt.Valid {
+            continue
+        }
+        if ancestor.ModerationState != chirpStateVisible && (!viewer.Valid || ancestor.UserID != viewer.UUID) {
+            continue
+        }
+        shown = append(shown, ancestor)
+    }
+    ancestorItems, err := cfg.listChirps(r.Context(), viewer, shown)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+
+    replies, err := cfg.dbQueries.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+        ReplyTo:         chirp.ID,
+        BeforeCreatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting replies", err)
+        return
+    }
+    replyItems, err := cfg.listChirps(r.Context(), viewer, replies)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+
+    thread := ChirpThread{
+        Ancestors: ancestorItems,
+        Chirp:     item,
+        Replies:   replyItems,
+    }
+    if len(replies) == int(limit) {
+        last := replies[len(replies)-1]
+        thread.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, thread)
+    return
+}
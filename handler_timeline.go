@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+type TimelinePage struct {
+    Chirps      []Chirp  `json:"chirps"`
+    NextCursor  string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request) {
+    // the home timeline is the user's own and followed chirps, with the
+    // rechirps they made
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    chirps, err := cfg.dbQueries.GetHomeTimeline(r.Context(), database.GetHomeTimelineParams{
+        UserID:          userID,
+        BeforeCreatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting timeline", err)
+        return
+    }
+
+    // leave out what the user cannot see, and rechirps of it
+    items, err := cfg.listChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting chirp data", err)
+        return
+    }
+    page := TimelinePage{Chirps: items}
+    if len(chirps) == int(limit) {
+        last := chirps[len(chirps)-1]
+        page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/CraigYanitski/server-test/internal/trends"
+)
+
+func (cfg *apiConfig) handlerGetTrends(w http.ResponseWriter, r *http.Request) {
+    // default to the hourly window
+    window := r.URL.Query().Get("window")
+    if window == "" {
+        window = "hour"
+    }
+    known := false
+    for _, tw := range trendWindows {
+        known = known || tw.name == window
+    }
+    if !known {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown trend window '%s'", window), nil)
+        return
+    }
+
+    // serve the cached ranking, which is empty until the first run finishes
+    result, ok := cfg.trends.get(window)
+    if !ok {
+        result = TrendResult{Window: window, Trends: []trends.Trend{}}
+    }
+    respondWithJSON(w, http.StatusOK, result)
+    return
+}
@@ -15,6 +15,7 @@ import (
 type InitUser struct {
     Email     string   `json:"email"`
     Password  string   `json:"password"`
+    Handle    string   `json:"handle"`
 }
 // user struct to recast database user and marshal responses
 type User struct {
@@ -22,8 +23,12 @@ type User struct {
     CreatedAt       time.Time  `json:"created_at"`
     UpdatedAt       time.Time  `json:"updated_at"`
     Email           string     `json:"email"`
-    HashedPassword  string     `json:"hashed_password,omitempty"`
+    Handle          string     `json:"handle"`
+    DisplayName     string     `json:"display_name"`
+    Bio             string     `json:"bio"`
+    AvatarURL       string     `json:"avatar_url,omitempty"`
     IsChirpyRed     bool       `json:"is_chirpy_red"`
+    Role            string     `json:"role"`
 }
 // valid user with additional access token
 type ValidUser struct{
@@ -32,6 +37,23 @@ type ValidUser struct{
     RefreshToken  string  `json:"refresh_token"`
 }
 
+// recast a database user to the JSON user shown to themselves, leaving out
+// the password hash
+func userFromDB(user database.User) User {
+    return User{
+        ID:             user.ID,
+        CreatedAt:      user.CreatedAt,
+        UpdatedAt:      user.UpdatedAt,
+        Email:          user.Email,
+        Handle:         user.Handle,
+        DisplayName:    user.DisplayName,
+        Bio:            user.Bio,
+        AvatarURL:      avatarURL(user),
+        IsChirpyRed:    user.IsChirpyRed,
+        Role:           user.Role,
+    }
+}
+
 // token struct
 type Token struct {
     Token  string  `json:"token"`
@@ -52,6 +74,14 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
         return
     }
 
+    // handles are optional, and generated when not given
+    if u.Handle != "" {
+        if err = validateHandle(u.Handle); err != nil {
+            respondWithError(w, http.StatusBadRequest, "invalid handle", err)
+            return
+        }
+    }
+
     // hash given password
     hash, err := auth.HashPassword(u.Password)
     if err != nil {
@@ -63,16 +93,21 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
     params := database.CreateUserParams{
         Email: u.Email, 
         HashedPassword: hash,
+        Handle: u.Handle,
     }
     user, err := cfg.dbQueries.CreateUser(r.Context(), params)
-    if err != nil {
+    if isUniqueViolation(err, "users_handle") {
+        respondWithError(w, http.StatusConflict, "handle already taken", err)
+        return
+    } else if isUniqueViolation(err, "users_email_key") {
+        respondWithError(w, http.StatusConflict, "email already registered", err)
+        return
+    } else if err != nil {
         respondWithError(w, http.StatusInternalServerError, "error creating user", err)
         return
     }
 
-    // empty password field to remove from marshalled JSON
-    user.HashedPassword = ""
-    respondWithJSON(w, http.StatusCreated, User(user))
+    respondWithJSON(w, http.StatusCreated, userFromDB(user))
     return
 }
 
@@ -127,19 +162,21 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 
     // recast database user to validated one, adding JWT
     validUser := &ValidUser{}
-    validUser.User = User(foundUser)
+    validUser.User = userFromDB(foundUser)
     validUser.Token = token
     validUser.RefreshToken = refreshToken.Token
 
     // check validity of password
-    err = auth.CheckPasswordHash(u.Password, validUser.HashedPassword)
+    err = auth.CheckPasswordHash(u.Password, foundUser.HashedPassword)
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "password incorrect", err)
         return
     }
 
-    // empty password field to remove from marshalled JSON
-    validUser.HashedPassword = ""
+    // suspended users cannot log in
+    if !cfg.checkNotSuspended(w, r, foundUser.ID) {
+        return
+    }
 
     // respond with user JSON
     respondWithJSON(w, http.StatusOK, validUser)
@@ -148,21 +185,15 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
     // check user authentication
-    token, err := auth.GetBearerToken(r.Header)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "", err)
-        return
-    }
-    id, err := auth.ValidateJWT(token, cfg.secret)
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, token, err)
+    id, ok := cfg.authenticateUser(w, r)
+    if !ok {
         return
     }
 
     // unmarshal the POST JSON and verify required fields are valid
     decoder := json.NewDecoder(r.Body)
     u := &InitUser{}
-    err = decoder.Decode(u)
+    err := decoder.Decode(u)
     if (err != nil) || (u.Email == "") || (u.Password == "") {
         respondWithError(
             w, 
@@ -192,9 +223,7 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    // empty password field to remove from marshalled JSON
-    user.HashedPassword = ""
-    respondWithJSON(w, http.StatusOK, User(user))
+    respondWithJSON(w, http.StatusOK, userFromDB(user))
     return
 }
 
@@ -209,6 +238,9 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
         respondWithError(w, http.StatusUnauthorized, "error invalid entry", err)
         return
     }
+    if !cfg.checkNotSuspended(w, r, refreshToken.ID) {
+        return
+    }
     newToken, err := auth.MakeJWT(refreshToken.ID, cfg.secret, time.Hour)
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "error unauthorised", err)
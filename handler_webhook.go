@@ -0,0 +1,404 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// webhooks a user can register
+const maxWebhooks = 10
+
+// webhook as seen by its owner. The secret is only shown when it is created.
+type Webhook struct {
+    ID          uuid.UUID  `json:"id"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+    Scope       string     `json:"scope"`
+    URL         string     `json:"url"`
+    EventTypes  []string   `json:"event_types"`
+    Active      bool       `json:"active"`
+    Secret      string     `json:"secret,omitempty"`
+}
+
+// webhook struct to unmarshal POST and PUT requests. The scope can only be
+// set when creating a webhook.
+type InitWebhook struct {
+    URL         string    `json:"url"`
+    EventTypes  []string  `json:"event_types"`
+    Scope       string    `json:"scope"`
+    Active      *bool     `json:"active"`
+}
+
+type WebhookDelivery struct {
+    ID             uuid.UUID         `json:"id"`
+    CreatedAt      time.Time         `json:"created_at"`
+    EventID        string            `json:"event_id"`
+    EventType      string            `json:"event_type"`
+    Status         string            `json:"status"`
+    // when a pending delivery is next attempted
+    NextAttemptAt  *time.Time        `json:"next_attempt_at"`
+    Attempts       []WebhookAttempt  `json:"attempts"`
+}
+
+type WebhookAttempt struct {
+    AttemptedAt  time.Time  `json:"attempted_at"`
+    StatusCode   *int32     `json:"status_code"`
+    Error        string     `json:"error,omitempty"`
+    DurationMs   int32      `json:"duration_ms"`
+}
+
+type WebhookDeliveryPage struct {
+    Deliveries  []WebhookDelivery  `json:"deliveries"`
+    NextCursor  string             `json:"next_cursor,omitempty"`
+}
+
+func webhookFromDB(hook database.Webhook) Webhook {
+    return Webhook{
+        ID:         hook.ID,
+        CreatedAt:  hook.CreatedAt,
+        UpdatedAt:  hook.UpdatedAt,
+        Scope:      hook.Scope,
+        URL:        hook.Url,
+        EventTypes: hook.EventTypes,
+        Active:     hook.Active,
+    }
+}
+
+func deliveryFromDB(delivery database.WebhookDelivery, attempts []database.WebhookAttempt) WebhookDelivery {
+    item := WebhookDelivery{
+        ID:        delivery.ID,
+        CreatedAt: delivery.CreatedAt,
+        EventID:   delivery.EventID,
+        EventType: delivery.EventType,
+        Status:    delivery.Status,
+        Attempts:  make([]WebhookAttempt, 0, len(attempts)),
+    }
+    if delivery.Status == deliveryPending {
+        item.NextAttemptAt = &delivery.NextAttemptAt
+    }
+    for _, attempt := range attempts {
+        a := WebhookAttempt{
+            AttemptedAt: attempt.AttemptedAt,
+            Error:       attempt.Error.String,
+            DurationMs:  attempt.DurationMs,
+        }
+        if attempt.StatusCode.Valid {
+            a.StatusCode = &attempt.StatusCode.Int32
+        }
+        item.Attempts = append(item.Attempts, a)
+    }
+    return item
+}
+
+// check a webhook's URL and events, returning what is wrong with them
+func validateWebhook(hook *InitWebhook, scope string) error {
+    if err := webhook.ValidateURL(hook.URL); err != nil {
+        return fmt.Errorf("invalid URL: %w", err)
+    }
+    if len(hook.EventTypes) == 0 {
+        return errors.New("no event types given")
+    }
+    for _, kind := range hook.EventTypes {
+        if !slices.Contains(webhookEventTypes[scope], kind) {
+            return fmt.Errorf("unknown event type '%s' for %s webhooks", kind, scope)
+        }
+    }
+    slices.Sort(hook.EventTypes)
+    hook.EventTypes = slices.Compact(hook.EventTypes)
+    return nil
+}
+
+func (cfg *apiConfig) handlerCreateWebhook(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+
+    // decode request body
+    decoder := json.NewDecoder(r.Body)
+    hook := &InitWebhook{}
+    err := decoder.Decode(hook)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if hook.Scope == "" {
+        hook.Scope = webhookScopeUser
+    }
+    if _, ok := webhookEventTypes[hook.Scope]; !ok {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown webhook scope '%s'", hook.Scope), nil)
+        return
+    }
+    if err = validateWebhook(hook, hook.Scope); err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error(), err)
+        return
+    }
+
+    // app webhooks see everything public, so only admins register them
+    if hook.Scope == webhookScopeApp {
+        user, err := cfg.dbQueries.GetUser(r.Context(), userID)
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error getting user", err)
+            return
+        }
+        if user.Role != roleAdmin {
+            respondWithError(w, http.StatusForbidden, "admin access required for app webhooks", nil)
+            return
+        }
+    }
+    hooks, err := cfg.dbQueries.GetWebhooksByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting webhooks", err)
+        return
+    }
+    if len(hooks) >= maxWebhooks {
+        respondWithError(w, http.StatusConflict, fmt.Sprintf("cannot register more than %d webhooks", maxWebhooks), nil)
+        return
+    }
+
+    secret, err := webhook.NewSecret()
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating webhook secret", err)
+        return
+    }
+    created, err := cfg.dbQueries.CreateWebhook(r.Context(), database.CreateWebhookParams{
+        UserID:     userID,
+        Scope:      hook.Scope,
+        Url:        hook.URL,
+        EventTypes: hook.EventTypes,
+        Secret:     secret,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error creating webhook", err)
+        return
+    }
+    item := webhookFromDB(created)
+    item.Secret = created.Secret
+    respondWithJSON(w, http.StatusCreated, item)
+    return
+}
+
+func (cfg *apiConfig) handlerGetWebhooks(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    hooks, err := cfg.dbQueries.GetWebhooksByUser(r.Context(), userID)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting webhooks", err)
+        return
+    }
+    items := make([]Webhook, 0, len(hooks))
+    for _, hook := range hooks {
+        items = append(items, webhookFromDB(hook))
+    }
+    respondWithJSON(w, http.StatusOK, items)
+    return
+}
+
+// get the webhook in the path, responding with an error unless the user owns
+// it
+func (cfg *apiConfig) ownWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Webhook, bool) {
+    webhookID, ok := parsePathUUID(w, r, "webhook_id")
+    if !ok {
+        return database.Webhook{}, false
+    }
+    hook, err := cfg.dbQueries.GetWebhook(r.Context(), webhookID)
+    if errors.Is(err, sql.ErrNoRows) || (err == nil && hook.UserID != userID) {
+        respondWithError(w, http.StatusNotFound, "webhook not found", err)
+        return database.Webhook{}, false
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting webhook", err)
+        return database.Webhook{}, false
+    }
+    return hook, true
+}
+
+func (cfg *apiConfig) handlerGetWebhook(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    hook, ok := cfg.ownWebhook(w, r, userID)
+    if !ok {
+        return
+    }
+    respondWithJSON(w, http.StatusOK, webhookFromDB(hook))
+    return
+}
+
+func (cfg *apiConfig) handlerUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    hook, ok := cfg.ownWebhook(w, r, userID)
+    if !ok {
+        return
+    }
+
+    // decode request body
+    decoder := json.NewDecoder(r.Body)
+    update := &InitWebhook{}
+    err := decoder.Decode(update)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding JSON", err)
+        return
+    }
+    if update.Scope != "" && update.Scope != hook.Scope {
+        respondWithError(w, http.StatusBadRequest, "the scope of a webhook cannot be changed", nil)
+        return
+    }
+    if err = validateWebhook(update, hook.Scope); err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error(), err)
+        return
+    }
+    active := hook.Active
+    if update.Active != nil {
+        active = *update.Active
+    }
+
+    updated, err := cfg.dbQueries.UpdateWebhook(r.Context(), database.UpdateWebhookParams{
+        Url:        update.URL,
+        EventTypes: update.EventTypes,
+        Active:     active,
+        ID:         hook.ID,
+        UserID:     userID,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "webhook not found", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating webhook", err)
+        return
+    }
+    respondWithJSON(w, http.StatusOK, webhookFromDB(updated))
+    return
+}
+
+func (cfg *apiConfig) handlerDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    webhookID, ok := parsePathUUID(w, r, "webhook_id")
+    if !ok {
+        return
+    }
+
+    // pending deliveries go with the webhook
+    deleted, err := cfg.dbQueries.DeleteWebhook(r.Context(), database.DeleteWebhookParams{ID: webhookID, UserID: userID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting webhook", err)
+        return
+    }
+    if deleted == 0 {
+        respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+        return
+    }
+    respondWithJSON(w, http.StatusNoContent, nil)
+    return
+}
+
+func (cfg *apiConfig) handlerGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateToken(w, r)
+    if !ok {
+        return
+    }
+    hook, ok := cfg.ownWebhook(w, r, userID)
+    if !ok {
+        return
+    }
+    cursor, limit, ok := parsePage(w, r)
+    if !ok {
+        return
+    }
+    deliveries, err := cfg.dbQueries.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+        WebhookID:       hook.ID,
+        BeforeCreatedAt: cursor.createdAt,
+        BeforeID:        cursor.id,
+        PageSize:        limit,
+    })
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting deliveries", err)
+        return
+    }
+
+    // attempts for the whole page in one query
+    ids := make([]uuid.UUID, 0, len(deliveries))
+    for _, delivery := range deliveries {
+        ids = append(ids, delivery.ID)
+    }
+    attempts, err := cfg.dbQueries.GetWebhookAttempts(r.Context(), ids)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting delivery attempts", err)
+        return
+    }
+    byDelivery := map[uuid.UUID][]database.WebhookAttempt{}
+    for _, attempt := range attempts {
+        byDelivery[attempt.DeliveryID] = append(byDelivery[attempt.DeliveryID], attempt)
+    }
+
+    page := WebhookDeliveryPage{Deliveries: make([]WebhookDelivery, 0, len(deliveries))}
+    for _, delivery := range deliveries {
+        page.Deliveries = append(page.Deliveries, deliveryFromDB(delivery, byDelivery[delivery.ID]))
+    }
+    if len(deliveries) == int(limit) {
+        last := deliveries[len(deliveries)-1]
+        page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+    }
+    respondWithJSON(w, http.StatusOK, page)
+    return
+}
+
+func (cfg *apiConfig) handlerRetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+    // authenticate user
+    userID, ok := cfg.authenticateUser(w, r)
+    if !ok {
+        return
+    }
+    hook, ok := cfg.ownWebhook(w, r, userID)
+    if !ok {
+        return
+    }
+    deliveryID, ok := parsePathUUID(w, r, "delivery_id")
+    if !ok {
+        return
+    }
+
+    // only dead deliveries are retried, since the rest are still on their way
+    // or done
+    delivery, err := cfg.dbQueries.RetryWebhookDelivery(r.Context(), database.RetryWebhookDeliveryParams{
+        ID:        deliveryID,
+        WebhookID: hook.ID,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        respondWithError(w, http.StatusNotFound, "dead delivery not found", err)
+        return
+    } else if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error retrying delivery", err)
+        return
+    }
+    attempts, err := cfg.dbQueries.GetWebhookAttempts(r.Context(), []uuid.UUID{delivery.ID})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting delivery attempts", err)
+        return
+    }
+    respondWithJSON(w, http.StatusAccepted, deliveryFromDB(delivery, attempts))
+    return
+}
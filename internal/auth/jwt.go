@@ -28,6 +28,12 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+    userID, _, err := ValidateJWTIssuedAt(tokenString, tokenSecret)
+    return userID, err
+}
+
+// validate the token, returning its user and when it was issued, to the second
+func ValidateJWTIssuedAt(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
     claims := &jwt.MapClaims{}
     token, err := jwt.ParseWithClaims(
         tokenString, 
@@ -37,16 +43,21 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
         },
     )
     if err != nil {
-        return uuid.New(), fmt.Errorf("error parsing JWT during validation: %s", err)
+        return uuid.New(), time.Time{}, fmt.Errorf("error parsing JWT during validation: %s", err)
     }
     if !token.Valid {
-        return uuid.New(), fmt.Errorf("error: invalid token")
+        return uuid.New(), time.Time{}, fmt.Errorf("error: invalid token")
     }
     user, err := claims.GetSubject()
     if err != nil {
-        return uuid.New(), fmt.Errorf("error getting claims during validation: %s", err)
+        return uuid.New(), time.Time{}, fmt.Errorf("error getting claims during validation: %s", err)
+    }
+    issuedAt, err := claims.GetIssuedAt()
+    if err != nil || issuedAt == nil {
+        return uuid.New(), time.Time{}, fmt.Errorf("error getting issue time during validation: %v", err)
     }
-    return uuid.Parse(user)
+    userID, err := uuid.Parse(user)
+    return userID, issuedAt.Time, err
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -54,6 +54,23 @@ func TestJWTValidationFail(t *testing.T) {
     }
 }
 
+func TestJWTIssuedAt(t *testing.T) {
+    id := uuid.New()
+    secret := "Issued secret token"
+    before := time.Now().Truncate(time.Second)
+    jwt, err := MakeJWT(id, secret, time.Minute)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    _, issuedAt, err := ValidateJWTIssuedAt(jwt, secret)
+    if err != nil {
+        t.Fatalf("error in JWT validation: %s", err)
+    }
+    if issuedAt.Before(before) || issuedAt.After(time.Now()) {
+        t.Fatalf("JWT issue time %s not when it was made", issuedAt)
+    }
+}
+
 func TestBearerToken(t *testing.T) {
     header := http.Header{}
     header["Authorization"] = []string{"JWT token here"}
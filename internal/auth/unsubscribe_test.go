@@ -0,0 +1,21 @@
+package auth
+
+import (
+    "testing"
+
+    "github.com/google/uuid"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+    userID := uuid.New()
+    token := MakeUnsubscribeToken(userID, "secret")
+    if err := CheckUnsubscribeToken(userID, token, "secret"); err != nil {
+        t.Fatalf("token for its own user rejected: %s", err)
+    }
+    if err := CheckUnsubscribeToken(uuid.New(), token, "secret"); err == nil {
+        t.Fatalf("token accepted for another user")
+    }
+    if err := CheckUnsubscribeToken(userID, token, "other secret"); err == nil {
+        t.Fatalf("token accepted with another secret")
+    }
+}
@@ -0,0 +1,63 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Entity is a hashtag or mention found in a chirp body. Start and End are
+// rune offsets, with Start at the sigil and End just past the last rune.
+type Entity struct {
+    Text   string
+    Start  int
+    End    int
+}
+
+func Hashtags(body string) []Entity {
+    entities := []Entity{}
+    for _, e := range extract(body, '#', isHashtagRune) {
+        // purely numeric tags are not hashtags, like "#1"
+        if strings.IndexFunc(e.Text, unicode.IsLetter) < 0 {
+            continue
+        }
+        entities = append(entities, e)
+    }
+    return entities
+}
+
+func Mentions(body string) []Entity {
+    return extract(body, '@', isMentionRune)
+}
+
+func extract(body string, sigil rune, valid func(rune) bool) []Entity {
+    entities := []Entity{}
+    runes := []rune(body)
+    for i := 0; i < len(runes); i++ {
+        // the sigil must start a word, so emails and "a#b" are skipped
+        if runes[i] != sigil || (i > 0 && isWordRune(runes[i-1])) {
+            continue
+        }
+        j := i + 1
+        for j < len(runes) && valid(runes[j]) {
+            j++
+        }
+        if j > i+1 {
+            entities = append(entities, Entity{Text: string(runes[i+1 : j]), Start: i, End: j})
+        }
+        i = j - 1
+    }
+    return entities
+}
+
+func isWordRune(r rune) bool {
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isHashtagRune(r rune) bool {
+    return isWordRune(r)
+}
+
+func isMentionRune(r rune) bool {
+    // user IDs contain hyphens
+    return isWordRune(r) || r == '-'
+}
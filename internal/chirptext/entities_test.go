@@ -0,0 +1,36 @@
+package chirptext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+    got := Hashtags("#Go is great, #go_lang! but not #1 or a#b")
+    want := []Entity{
+        {Text: "Go", Start: 0, End: 3},
+        {Text: "go_lang", Start: 14, End: 22},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("hashtags parsed as %v, expected %v", got, want)
+    }
+}
+
+func TestHashtagsRuneOffsets(t *testing.T) {
+    got := Hashtags("café #crème")
+    want := []Entity{{Text: "crème", Start: 5, End: 11}}
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("hashtags parsed as %v, expected %v", got, want)
+    }
+}
+
+func TestMentions(t *testing.T) {
+    got := Mentions("hi @alice and @bob-2, mail me at me@example.com @")
+    want := []Entity{
+        {Text: "alice", Start: 3, End: 9},
+        {Text: "bob-2", Start: 14, End: 20},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("mentions parsed as %v, expected %v", got, want)
+    }
+}
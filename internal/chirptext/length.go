@@ -0,0 +1,34 @@
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is what every link counts for, however long it is
+const URLWeight = 23
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// Normalize puts a chirp body in NFC form, so the same text is always stored
+// and counted the same way.
+func Normalize(body string) string {
+    return norm.NFC.String(body)
+}
+
+// Length counts a chirp body in user-perceived characters, with links
+// counted as URLWeight each. Bodies should be normalized first.
+func Length(body string) int {
+    length := 0
+    last := 0
+    for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+        // punctuation ending a sentence is not part of the link
+        end := loc[0] + len(strings.TrimRight(body[loc[0]:loc[1]], ".,:;!?)'\""))
+        length += uniseg.GraphemeClusterCount(body[last:loc[0]]) + URLWeight
+        last = end
+    }
+    return length + uniseg.GraphemeClusterCount(body[last:])
+}
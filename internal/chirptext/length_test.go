@@ -0,0 +1,37 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+    cases := []struct {
+        body  string
+        want  int
+    }{
+        {"hello", 5},
+        {strings.Repeat("😀", 40), 40},
+        // family emoji and flags are single characters made of many runes
+        {"👨‍👩‍👧‍👦🇳🇿", 2},
+        {"see https://example.com/a/very/long/path/indeed.", 4 + URLWeight + 1},
+        {"http://a.io http://b.io", 2*URLWeight + 1},
+    }
+    for _, c := range cases {
+        if got := Length(c.body); got != c.want {
+            t.Errorf("length of %q counted as %d, expected %d", c.body, got, c.want)
+        }
+    }
+}
+
+func TestNormalize(t *testing.T) {
+    // e followed by a combining acute accent composes to one rune
+    decomposed := "cafe\u0301"
+    got := Normalize(decomposed)
+    if got != "café" {
+        t.Fatalf("normalized %q to %q", decomposed, got)
+    }
+    if Length(got) != 4 || Length(decomposed) != 4 {
+        t.Fatalf("accented word counted as %d and %d, expected 4", Length(got), Length(decomposed))
+    }
+}
@@ -7,29 +7,43 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of, moderation_state, moderation_rules, visibility, reply_to, thread_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body            string
+	UserID          uuid.UUID
+	QuoteOf         uuid.NullUUID
+	ModerationState string
+	ModerationRules []string
+	Visibility      string
+	ReplyTo         uuid.NullUUID
+	ThreadID        uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.QuoteOf, arg.ModerationState, pq.Array(arg.ModerationRules), arg.Visibility, arg.ReplyTo, arg.ThreadID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,18 +51,31 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :one
-DELETE FROM chirps
+const getChirp = `-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
 WHERE id = $1
-RETURNING id, created_at, updated_at, body, user_id
+AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, deleteChirp, id)
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -56,17 +83,32 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
 	)
 	return i, err
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
 WHERE id = $1
+AND deleted_at IS NULL
+FOR UPDATE
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -74,12 +116,64 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
+	)
+	return i, err
+}
+
+const getChirpIncludingDeleted = `-- name: GetChirpIncludingDeleted :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpIncludingDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpIncludingDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps 
+WHERE moderation_state = 'visible'
+AND visibility IN ('public', 'followers')
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
 ORDER BY created_at
 `
 
@@ -98,6 +192,117 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
+WHERE id = ANY($1::UUID[])
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByModerationState = `-- name: GetChirpsByModerationState :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
+WHERE moderation_state = $1
+AND deleted_at IS NULL
+ORDER BY created_at
+`
+
+func (q *Queries) GetChirpsByModerationState(ctx context.Context, moderationState string) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByModerationState, moderationState)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -113,8 +318,15 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUser = `-- name: GetChirpsByUser :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps 
 WHERE user_id = $1
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
 `
 
 func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -132,6 +344,19 @@ func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chir
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -146,6 +371,229 @@ func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chir
 	return items, nil
 }
 
+const getDeletedChirps = `-- name: GetDeletedChirps :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) GetDeletedChirps(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHomeTimeline = `-- name: GetHomeTimeline :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count FROM chirps
+WHERE (user_id = $1 OR user_id IN (
+    SELECT followee_id FROM follows
+    WHERE follower_id = $1
+))
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
+AND (created_at, id) < ($2::TIMESTAMP, $3::UUID)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetHomeTimelineParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageSize        int32
+}
+
+// a page of the chirps and rechirps of the user and the accounts they follow,
+// newest first, after the given cursor. Visibility is checked for the viewer
+// afterwards.
+func (q *Queries) GetHomeTimeline(ctx context.Context, arg GetHomeTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getHomeTimeline, arg.UserID, arg.BeforeCreatedAt, arg.BeforeID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+			&i.ModerationState,
+			pq.Array(&i.ModerationRules),
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ReplyTo,
+			&i.ThreadID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getViewableChirpIDs = `-- name: GetViewableChirpIDs :many
+SELECT id FROM chirps
+WHERE id = ANY($1::UUID[])
+AND (
+    user_id = $2
+    OR (visibility = 'followers' AND user_id IN (
+        SELECT followee_id FROM follows
+        WHERE follower_id = $2
+    ))
+    OR (visibility = 'direct' AND id IN (
+        SELECT chirp_id FROM chirp_mentions
+        WHERE user_id = $2
+    ))
+)
+`
+
+type GetViewableChirpIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+// which of the given followers and direct chirps the viewer can see, as the
+// author, a follower of the author or a mentioned user
+func (q *Queries) GetViewableChirpIDs(ctx context.Context, arg GetViewableChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getViewableChirpIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirpMedia = `-- name: PurgeDeletedChirpMedia :many
+DELETE FROM media_attachments
+WHERE chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < NOW() - $1::INTEGER * INTERVAL '1 second'
+)
+RETURNING id, created_at, user_id, content_type, width, height, size_bytes, blob_key, thumbnail_key, chirp_id, position
+`
+
+// remove the media of chirps deleted before the retention period, returning
+// them so their blobs can be removed too
+func (q *Queries) PurgeDeletedChirpMedia(ctx context.Context, retentionSeconds int32) ([]MediaAttachment, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedChirpMedia, retentionSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaAttachment
+	for rows.Next() {
+		var i MediaAttachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.BlobKey,
+			&i.ThumbnailKey,
+			&i.ChirpID,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < NOW() - $1::INTEGER * INTERVAL '1 second'
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, retentionSeconds int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, retentionSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE from chirps
 `
@@ -154,3 +602,167 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND user_id = $2
+AND moderation_state <> 'hidden'
+AND deleted_at >= NOW() - $3::INTEGER * INTERVAL '1 second'
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	WindowSeconds int32
+}
+
+// only the author can restore, only inside the restore window, and never a
+// chirp removed by a moderator
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.WindowSeconds)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
+	)
+	return i, err
+}
+
+const setChirpModerationState = `-- name: SetChirpModerationState :one
+UPDATE chirps
+SET moderation_state = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count
+`
+
+type SetChirpModerationStateParams struct {
+	ID              uuid.UUID
+	ModerationState string
+}
+
+func (q *Queries) SetChirpModerationState(ctx context.Context, arg SetChirpModerationStateParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpModerationState, arg.ID, arg.ModerationState)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :one
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count
+`
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, softDeleteChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1,
+    updated_at = NOW(),
+    edited_at = NOW(),
+    moderation_state = $2,
+    moderation_rules = $3
+WHERE id = $4
+AND deleted_at IS NULL
+AND created_at >= NOW() - $5::INTEGER * INTERVAL '1 second'
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of, quote_of, rechirp_count, quote_count, edited_at, moderation_state, moderation_rules, deleted_at, visibility, reply_to, thread_id, reply_count
+`
+
+type UpdateChirpBodyParams struct {
+	Body            string
+	ModerationState string
+	ModerationRules []string
+	ID              uuid.UUID
+	WindowSeconds   int32
+}
+
+// only chirps still inside the edit window are updated
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ModerationState, pq.Array(arg.ModerationRules), arg.ID, arg.WindowSeconds)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+		&i.ModerationState,
+		pq.Array(&i.ModerationRules),
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ReplyTo,
+		&i.ThreadID,
+		&i.ReplyCount,
+	)
+	return i, err
+}
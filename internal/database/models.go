@@ -6,17 +6,195 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Chirp struct {
+type Bookmark struct {
+	UserID       uuid.UUID
+	ChirpID      uuid.UUID
+	CollectionID uuid.NullUUID
+	CreatedAt    time.Time
+}
+
+type BookmarkCollection struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+type Chirp struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Body            string
+	UserID          uuid.UUID
+	LikeCount       int32
+	RechirpOf       uuid.NullUUID
+	QuoteOf         uuid.NullUUID
+	RechirpCount    int32
+	QuoteCount      int32
+	EditedAt        sql.NullTime
+	ModerationState string
+	ModerationRules []string
+	DeletedAt       sql.NullTime
+	Visibility      string
+	ReplyTo         uuid.NullUUID
+	ThreadID        uuid.NullUUID
+	ReplyCount      int32
+}
+
+type ChirpHashtag struct {
+	ChirpID    uuid.UUID
+	Tag        string
+	StartIndex int32
+	EndIndex   int32
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID    uuid.UUID
+	UserID     uuid.UUID
+	StartIndex int32
+	EndIndex   int32
+}
+
+type ChirpPin struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
 	Body      string
+	CreatedAt time.Time
+}
+
+type Conversation struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy uuid.NullUUID
+	IsGroup   bool
+	DirectKey sql.NullString
+}
+
+type ConversationParticipant struct {
+	ConversationID    uuid.UUID
+	UserID            uuid.UUID
+	JoinedAt          time.Time
+	LeftAt            sql.NullTime
+	Muted             bool
+	LastReadMessageID uuid.NullUUID
+	LastReadAt        sql.NullTime
+}
+
+type Draft struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Body       string
+	QuoteOf    uuid.NullUUID
+	MediaIds   []uuid.UUID
+	PublishAt  sql.NullTime
+	LastError  sql.NullString
+	Visibility string
+	ReplyTo    uuid.NullUUID
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type HashtagBucket struct {
+	Tag         string
+	BucketStart time.Time
+	Uses        int32
+}
+
+type MediaAttachment struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	ContentType  string
+	Width        int32
+	Height       int32
+	SizeBytes    int32
+	BlobKey      string
+	ThumbnailKey string
+	ChirpID      uuid.NullUUID
+	Position     sql.NullInt32
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.NullUUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ModeratorID  uuid.NullUUID
+	Action       string
+	ChirpID      uuid.NullUUID
+	TargetUserID uuid.NullUUID
+	ReportID     uuid.NullUUID
+	Note         string
+}
+
+type Notification struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Type       string
+	ChirpID    uuid.NullUUID
+	GroupKey   sql.NullString
+	ActorIds   []uuid.UUID
+	ActorCount int32
+	ReadAt     sql.NullTime
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	ClosesAt  time.Time
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Body     string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
 	UserID    uuid.UUID
+	Position  int32
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +206,85 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
-type User struct {
+type Report struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ReporterID     uuid.UUID
+	ChirpID        uuid.UUID
+	ChirpAuthorID  uuid.UUID
+	ChirpBody      string
+	Reason         string
+	Details        string
+	Status         string
+	Resolution     sql.NullString
+	ResolutionNote sql.NullString
+	ResolvedBy     uuid.NullUUID
+	ResolvedAt     sql.NullTime
+}
+
+type User struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	IsChirpyRed      bool
+	Role             string
+	SuspendedAt      sql.NullTime
+	SuspendedUntil   sql.NullTime
+	SuspensionReason sql.NullString
+	Handle           string
+	DisplayName      string
+	Bio              string
+	AvatarMediaID    uuid.NullUUID
+	DigestFrequency  string
+	DigestSentAt     time.Time
+	TokensValidAfter sql.NullTime
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Webhook struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Scope      string
+	Url        string
+	EventTypes []string
+	Secret     string
+	Active     bool
+}
+
+type WebhookAttempt struct {
+	ID          uuid.UUID
+	DeliveryID  uuid.UUID
+	AttemptedAt time.Time
+	StatusCode  sql.NullInt32
+	Error       sql.NullString
+	DurationMs  int32
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	WebhookID     uuid.UUID
+	EventID       string
+	EventType     string
+	EventData     json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
 }
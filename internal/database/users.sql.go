@@ -7,29 +7,34 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    COALESCE(NULLIF($3::TEXT, ''), 'user_' || substr(md5(random()::TEXT), 1, 10))
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
+// users who do not choose a handle get a generated one
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,12 +43,103 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const getActiveSuspension = `-- name: GetActiveSuspension :one
+SELECT suspended_until, suspension_reason FROM users
+WHERE id = $1
+AND suspended_at IS NOT NULL
+AND (suspended_until IS NULL OR suspended_until > NOW())
+`
+
+type GetActiveSuspensionRow struct {
+	SuspendedUntil   sql.NullTime
+	SuspensionReason sql.NullString
+}
+
+func (q *Queries) GetActiveSuspension(ctx context.Context, id uuid.UUID) (GetActiveSuspensionRow, error) {
+	row := q.db.QueryRowContext(ctx, getActiveSuspension, id)
+	var i GetActiveSuspensionRow
+	err := row.Scan(
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+	)
+	return i, err
+}
+
+const getExistingUserIDs = `-- name: GetExistingUserIDs :many
+SELECT id FROM users
+WHERE id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetExistingUserIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getExistingUserIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users 
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after FROM users 
 WHERE email=$1
 `
 
@@ -57,10 +153,222 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after FROM users
+WHERE LOWER(handle) = LOWER($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+// lock the user's row, to serialize changes counted against their limits
+func (q *Queries) GetUserForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
 
+const getUserIDsByHandles = `-- name: GetUserIDsByHandles :many
+SELECT id, LOWER(handle) AS handle FROM users
+WHERE LOWER(handle) = ANY($1::TEXT[])
+`
+
+type GetUserIDsByHandlesRow struct {
+	ID     uuid.UUID
+	Handle string
+}
+
+// users for a batch of lowercase handles
+func (q *Queries) GetUserIDsByHandles(ctx context.Context, handles []string) ([]GetUserIDsByHandlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserIDsByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserIDsByHandlesRow
+	for rows.Next() {
+		var i GetUserIDsByHandlesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserProfileCounts = `-- name: GetUserProfileCounts :one
+SELECT
+    (SELECT COUNT(*) FROM chirps
+     WHERE chirps.user_id = $1
+     AND chirps.moderation_state = 'visible'
+     AND chirps.deleted_at IS NULL
+     AND chirps.visibility IN ('public', 'unlisted')
+     AND (chirps.rechirp_of IS NULL OR EXISTS (
+         SELECT 1 FROM chirps originals
+         WHERE originals.id = chirps.rechirp_of
+         AND originals.moderation_state = 'visible'
+         AND originals.deleted_at IS NULL
+         AND originals.visibility IN ('public', 'unlisted')
+     ))) AS chirp_count,
+    (SELECT COUNT(*) FROM follows
+     WHERE follows.followee_id = $1) AS follower_count,
+    (SELECT COUNT(*) FROM follows
+     WHERE follows.follower_id = $1) AS following_count
+`
+
+type GetUserProfileCountsRow struct {
+	ChirpCount     int64
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+// the chirp count is of what the user's chirp list shows an anonymous viewer,
+// whoever is asking, so it reveals nothing about restricted chirps
+func (q *Queries) GetUserProfileCounts(ctx context.Context, id uuid.UUID) (GetUserProfileCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfileCounts, id)
+	var i GetUserProfileCountsRow
+	err := row.Scan(
+		&i.ChirpCount,
+		&i.FollowerCount,
+		&i.FollowingCount,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after FROM users
+WHERE id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Role,
+			&i.SuspendedAt,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarMediaID,
+			&i.DigestFrequency,
+			&i.DigestSentAt,
+			&i.TokensValidAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const promoteAdminByEmail = `-- name: PromoteAdminByEmail :execrows
+UPDATE users
+SET updated_at = NOW(),
+    role = 'admin'
+WHERE email = $1
+AND role <> 'admin'
+`
+
+// make the account with this email an admin, to bootstrap the first one
+func (q *Queries) PromoteAdminByEmail(ctx context.Context, email string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, promoteAdminByEmail, email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE from users
 `
@@ -70,13 +378,127 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET updated_at = NOW(),
+    role = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET updated_at = NOW(),
+    suspended_at = NOW(),
+    suspended_until = NOW() + NULLIF($1::INTEGER, 0) * INTERVAL '1 second',
+    suspension_reason = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
+`
+
+type SuspendUserParams struct {
+	DurationSeconds  int32
+	SuspensionReason sql.NullString
+	ID               uuid.UUID
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.DurationSeconds, arg.SuspensionReason, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET updated_at = NOW(),
+    suspended_at = NULL,
+    suspended_until = NULL,
+    suspension_reason = NULL
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
 SET updated_at = NOW(),
     email = $2,
     hashed_password = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
 `
 
 type UpdateUserParams struct {
@@ -95,6 +517,61 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = NOW(),
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    avatar_media_id = $5
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
+`
+
+type UpdateUserProfileParams struct {
+	ID            uuid.UUID
+	Handle        string
+	DisplayName   string
+	Bio           string
+	AvatarMediaID uuid.NullUUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.ID, arg.Handle, arg.DisplayName, arg.Bio, arg.AvatarMediaID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
@@ -103,7 +580,7 @@ const updateUserToRed = `-- name: UpdateUserToRed :one
 UPDATE users 
 SET is_chirpy_red = true
 WHERE id = $1 
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_at, suspended_until, suspension_reason, handle, display_name, bio, avatar_media_id, digest_frequency, digest_sent_at, tokens_valid_after
 `
 
 func (q *Queries) UpdateUserToRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -116,6 +593,17 @@ func (q *Queries) UpdateUserToRed(ctx context.Context, id uuid.UUID) (User, erro
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.DigestFrequency,
+		&i.DigestSentAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Event is something that happened, shared by every server instance. IDs are
+// unique but not ordered, so resuming goes by position in the replay buffer.
+type Event struct {
+    ID    string           `json:"id"`
+    Type  string           `json:"type"`
+    Data  json.RawMessage  `json:"data"`
+}
+
+// Subscription receives events published after it was made. C is closed when
+// the subscriber falls too far behind or unsubscribes.
+type Subscription struct {
+    C   <-chan Event
+    c   chan Event
+}
+
+// Broker fans events out to subscribers, keeping the last few for those
+// resuming after a dropped connection.
+type Broker struct {
+    mu       sync.Mutex
+    replay   []Event
+    next     int
+    full     bool
+    pending  int
+    subs     map[*Subscription]struct{}
+}
+
+// NewBroker keeps replaySize events for resuming, and lets each subscriber
+// fall pending events behind before it is dropped.
+func NewBroker(replaySize, pending int) *Broker {
+    return &Broker{
+        replay:   make([]Event, replaySize),
+        pending:  pending,
+        subs:     map[*Subscription]struct{}{},
+    }
+}
+
+// Publish sends an event to every subscriber. Subscribers that are not keeping
+// up are dropped rather than blocking everyone else; they can resume from
+// the replay buffer.
+func (b *Broker) Publish(event Event) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if len(b.replay) > 0 {
+        b.replay[b.next] = event
+        b.next = (b.next + 1) % len(b.replay)
+        if b.next == 0 {
+            b.full = true
+        }
+    }
+    for sub := range b.subs {
+        select {
+        case sub.c <- event:
+        default:
+            delete(b.subs, sub)
+            close(sub.c)
+        }
+    }
+}
+
+// Subscribe to events after lastID, returning the buffered events that were
+// missed. Resumed is false if lastID is no longer buffered, in which case
+// the subscriber may have missed events. An empty lastID starts afresh.
+func (b *Broker) Subscribe(lastID string) (sub *Subscription, missed []Event, resumed bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    c := make(chan Event, b.pending)
+    sub = &Subscription{C: c, c: c}
+    b.subs[sub] = struct{}{}
+    if lastID == "" {
+        return sub, nil, true
+    }
+    buffered := b.buffered()
+    for i, event := range buffered {
+        if event.ID == lastID {
+            return sub, append([]Event{}, buffered[i+1:]...), true
+        }
+    }
+    return sub, nil, false
+}
+
+// Unsubscribe stops a subscription, closing its channel if still open.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if _, ok := b.subs[sub]; ok {
+        delete(b.subs, sub)
+        close(sub.c)
+    }
+}
+
+// buffered events, oldest first
+func (b *Broker) buffered() []Event {
+    if !b.full {
+        return b.replay[:b.next]
+    }
+    return append(append([]Event{}, b.replay[b.next:]...), b.replay[:b.next]...)
+}
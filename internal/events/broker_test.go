@@ -0,0 +1,70 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+)
+
+func publishN(b *Broker, n int) {
+    for i := range n {
+        b.Publish(Event{ID: fmt.Sprint(i), Type: "test"})
+    }
+}
+
+func TestSubscribeReceives(t *testing.T) {
+    b := NewBroker(4, 8)
+    sub, missed, resumed := b.Subscribe("")
+    if len(missed) != 0 || !resumed {
+        t.Fatalf("fresh subscription got %d missed events, resumed %v", len(missed), resumed)
+    }
+    publishN(b, 3)
+    for i := range 3 {
+        event := <-sub.C
+        if event.ID != fmt.Sprint(i) {
+            t.Fatalf("event %d has ID %s", i, event.ID)
+        }
+    }
+}
+
+func TestResume(t *testing.T) {
+    b := NewBroker(4, 8)
+    publishN(b, 6)
+    cases := []struct {
+        lastID   string
+        missed   []string
+        resumed  bool
+    }{
+        {"2", []string{"3", "4", "5"}, true},
+        {"5", []string{}, true},
+        {"1", nil, false},
+    }
+    for _, c := range cases {
+        _, missed, resumed := b.Subscribe(c.lastID)
+        if resumed != c.resumed {
+            t.Fatalf("resuming from %s: resumed %v, expected %v", c.lastID, resumed, c.resumed)
+        }
+        if len(missed) != len(c.missed) {
+            t.Fatalf("resuming from %s: got %d missed events, expected %d", c.lastID, len(missed), len(c.missed))
+        }
+        for i, event := range missed {
+            if event.ID != c.missed[i] {
+                t.Fatalf("resuming from %s: missed event %d has ID %s, expected %s", c.lastID, i, event.ID, c.missed[i])
+            }
+        }
+    }
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+    b := NewBroker(4, 2)
+    slow, _, _ := b.Subscribe("")
+    publishN(b, 3)
+    count := 0
+    for range slow.C {
+        count++
+    }
+    if count != 2 {
+        t.Fatalf("slow subscriber got %d events before being dropped, expected 2", count)
+    }
+    // unsubscribing again is harmless
+    b.Unsubscribe(slow)
+}
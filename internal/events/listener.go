@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// TypeReset is published when events may have been missed, for instance
+// while the connection to Postgres was being re-established. Subscribers
+// should refetch whatever they show.
+const TypeReset = "reset"
+
+// Listen relays events sent with pg_notify on a Postgres channel to the
+// broker until the context is done. Every instance listens, including the
+// one that sent the event, so publishing only ever goes through Postgres.
+func Listen(ctx context.Context, connStr, channel string, broker *Broker) error {
+    listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+        if err != nil {
+            log.Printf("event listener: %s", err)
+        }
+    })
+    defer listener.Close()
+    if err := listener.Listen(channel); err != nil {
+        return err
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case notification := <-listener.Notify:
+            // a nil notification follows a reconnect, after which anything
+            // sent in between is lost
+            if notification == nil {
+                broker.Publish(Event{ID: uuid.NewString(), Type: TypeReset, Data: json.RawMessage("{}")})
+                continue
+            }
+            event := Event{}
+            if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+                log.Printf("event listener: bad event on %s: %s", channel, err)
+                continue
+            }
+            broker.Publish(event)
+        case <-time.After(90 * time.Second):
+            // check the connection is still alive
+            go listener.Ping()
+        }
+    }
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer drops each message into a directory as an .eml file, for
+// development and tests.
+type FileMailer struct {
+    dir  string
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+    dir, err := filepath.Abs(dir)
+    if err != nil {
+        return nil, err
+    }
+    if err = os.MkdirAll(dir, 0o755); err != nil {
+        return nil, err
+    }
+    return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+    data, err := msg.Bytes()
+    if err != nil {
+        return err
+    }
+    // name files by time so they list in the order they were sent
+    f, err := os.CreateTemp(m.dir, fmt.Sprintf("%s-*.eml", time.Now().UTC().Format("20060102T150405.000000000")))
+    if err != nil {
+        return err
+    }
+    if _, err = f.Write(data); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testMessage() Message {
+    return Message{
+        From:     "Chirpy <digest@chirpy.test>",
+        To:       "walt@example.com",
+        Subject:  "Your weekly digest – 3 new",
+        Text:     "Hello, plain text",
+        HTML:     "<p>Hello, <b>HTML</b></p>",
+        Headers:  map[string]string{"list-unsubscribe": "<https://chirpy.test/unsubscribe>"},
+    }
+}
+
+// parse a formatted message and return its headers and parts by type
+func parseMessage(t *testing.T, data []byte) (mail.Header, map[string]string) {
+    msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+    if err != nil {
+        t.Fatalf("error parsing message: %s", err)
+    }
+    mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+    if err != nil || mediaType != "multipart/alternative" {
+        t.Fatalf("message has content type '%s', %v", mediaType, err)
+    }
+    parts := map[string]string{}
+    reader := multipart.NewReader(msg.Body, params["boundary"])
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            t.Fatalf("error reading part: %s", err)
+        }
+        body, _ := io.ReadAll(part)
+        contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+        parts[contentType] = string(body)
+    }
+    return msg.Header, parts
+}
+
+func TestMessageBytes(t *testing.T) {
+    data, err := testMessage().Bytes()
+    if err != nil {
+        t.Fatalf("error formatting message: %s", err)
+    }
+    header, parts := parseMessage(t, data)
+    subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+    if err != nil || subject != testMessage().Subject {
+        t.Fatalf("subject decoded as '%s', %v", subject, err)
+    }
+    if header.Get("List-Unsubscribe") != "<https://chirpy.test/unsubscribe>" {
+        t.Fatalf("extra header missing, got '%s'", header.Get("List-Unsubscribe"))
+    }
+    if parts["text/plain"] != "Hello, plain text" {
+        t.Fatalf("text part is '%s'", parts["text/plain"])
+    }
+    if parts["text/html"] != "<p>Hello, <b>HTML</b></p>" {
+        t.Fatalf("HTML part is '%s'", parts["text/html"])
+    }
+}
+
+func TestHeaderInjection(t *testing.T) {
+    msg := testMessage()
+    msg.To = "walt@example.com\r\nBcc: everyone@example.com"
+    if _, err := msg.Bytes(); err == nil {
+        t.Fatalf("header with a line break was formatted")
+    }
+}
+
+func TestFileMailer(t *testing.T) {
+    dir := t.TempDir()
+    mailer, err := NewFileMailer(dir)
+    if err != nil {
+        t.Fatalf("error creating mailer: %s", err)
+    }
+    for range 2 {
+        if err = mailer.Send(context.Background(), testMessage()); err != nil {
+            t.Fatalf("error sending message: %s", err)
+        }
+    }
+    files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+    if len(files) != 2 {
+        t.Fatalf("found %d files, expected 2", len(files))
+    }
+    data, _ := os.ReadFile(files[0])
+    header, _ := parseMessage(t, data)
+    if header.Get("To") != "walt@example.com" {
+        t.Fatalf("dropped message is to '%s'", header.Get("To"))
+    }
+}
@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is an email with both a plain text and an HTML body.
+type Message struct {
+    From     string
+    To       string
+    Subject  string
+    Text     string
+    HTML     string
+    // extra headers, such as List-Unsubscribe
+    Headers  map[string]string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+    Send(ctx context.Context, msg Message) error
+}
+
+// Bytes formats the message as multipart/alternative MIME, with the plain
+// text part first so clients prefer the HTML.
+func (m Message) Bytes() ([]byte, error) {
+    boundary, err := randomBoundary()
+    if err != nil {
+        return nil, err
+    }
+    buf := &bytes.Buffer{}
+    headers := map[string]string{
+        "From":         m.From,
+        "To":           m.To,
+        "Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+        "Date":         time.Now().UTC().Format(time.RFC1123Z),
+        "MIME-Version": "1.0",
+        "Content-Type": fmt.Sprintf("multipart/alternative; boundary=%s", boundary),
+    }
+    for name, value := range m.Headers {
+        headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        // a line break would let a value add headers of its own
+        if strings.ContainsAny(headers[name], "\r\n") {
+            return nil, fmt.Errorf("line break in %s header", name)
+        }
+        fmt.Fprintf(buf, "%s: %s\r\n", name, headers[name])
+    }
+    buf.WriteString("\r\n")
+
+    for _, part := range []struct{ contentType, body string }{
+        {"text/plain; charset=utf-8", m.Text},
+        {"text/html; charset=utf-8", m.HTML},
+    } {
+        fmt.Fprintf(buf, "--%s\r\n", boundary)
+        fmt.Fprintf(buf, "Content-Type: %s\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", part.contentType)
+        qp := quotedprintable.NewWriter(buf)
+        if _, err = qp.Write([]byte(part.body)); err != nil {
+            return nil, err
+        }
+        if err = qp.Close(); err != nil {
+            return nil, err
+        }
+        buf.WriteString("\r\n")
+    }
+    fmt.Fprintf(buf, "--%s--\r\n", boundary)
+    return buf.Bytes(), nil
+}
+
+func randomBoundary() (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
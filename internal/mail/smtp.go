@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPConfig is where to relay mail and how to log in. Authentication is
+// skipped without a username.
+type SMTPConfig struct {
+    Addr      string
+    Username  string
+    Password  string
+}
+
+// SMTPMailer relays messages through an SMTP server.
+type SMTPMailer struct {
+    cfg   SMTPConfig
+    auth  smtp.Auth
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+    if cfg.Addr == "" {
+        return nil, errors.New("SMTP address must be set")
+    }
+    host, _, err := net.SplitHostPort(cfg.Addr)
+    if err != nil {
+        return nil, err
+    }
+    m := &SMTPMailer{cfg: cfg}
+    if cfg.Username != "" {
+        m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+    }
+    return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+    from, err := mail.ParseAddress(msg.From)
+    if err != nil {
+        return err
+    }
+    to, err := mail.ParseAddress(msg.To)
+    if err != nil {
+        return err
+    }
+    data, err := msg.Bytes()
+    if err != nil {
+        return err
+    }
+    // net/smtp takes no context, so only check it before sending
+    if err = ctx.Err(); err != nil {
+        return err
+    }
+    return smtp.SendMail(m.cfg.Addr, m.auth, from.Address, []string{to.Address}, data)
+}
@@ -0,0 +1,158 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+    // MaxUploadBytes is the largest image accepted
+    MaxUploadBytes = 5 << 20
+    // MaxPixels bounds the decoded size, so small files cannot expand into
+    // huge images
+    MaxPixels = 40_000_000
+    // ThumbnailSize is the longest side of a thumbnail
+    ThumbnailSize = 320
+)
+
+var (
+    ErrTooLarge         = errors.New("image is too large")
+    ErrUnsupportedType  = errors.New("unsupported image type")
+)
+
+// content types accepted for upload
+var imageTypes = map[string]string{
+    "image/jpeg":  "jpeg",
+    "image/png":   "png",
+    "image/gif":   "gif",
+}
+
+// Image is a validated upload, re-encoded without its metadata, along with
+// a thumbnail in the same format.
+type Image struct {
+    ContentType  string
+    Extension    string
+    Width        int
+    Height       int
+    Data         []byte
+    Thumbnail    []byte
+}
+
+// Process validates an uploaded image by sniffing its content rather than
+// trusting the declared type, then decodes and re-encodes it. Re-encoding
+// drops EXIF and any other metadata, and only the first frame of a GIF is
+// kept.
+func Process(data []byte) (Image, error) {
+    if len(data) > MaxUploadBytes {
+        return Image{}, ErrTooLarge
+    }
+    contentType := http.DetectContentType(data)
+    format, ok := imageTypes[contentType]
+    if !ok {
+        return Image{}, ErrUnsupportedType
+    }
+
+    // check the dimensions before decoding the pixels
+    config, configFormat, err := image.DecodeConfig(bytes.NewReader(data))
+    if err != nil || configFormat != format {
+        return Image{}, fmt.Errorf("invalid %s image: %w", format, err)
+    }
+    if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+        return Image{}, ErrTooLarge
+    }
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return Image{}, fmt.Errorf("invalid %s image: %w", format, err)
+    }
+
+    clean, err := encode(img, format)
+    if err != nil {
+        return Image{}, err
+    }
+    thumb, err := encode(Thumbnail(img, ThumbnailSize), format)
+    if err != nil {
+        return Image{}, err
+    }
+    bounds := img.Bounds()
+    ext := format
+    if ext == "jpeg" {
+        ext = "jpg"
+    }
+    return Image{
+        ContentType: contentType,
+        Extension:   ext,
+        Width:       bounds.Dx(),
+        Height:      bounds.Dy(),
+        Data:        clean,
+        Thumbnail:   thumb,
+    }, nil
+}
+
+func encode(img image.Image, format string) ([]byte, error) {
+    buf := &bytes.Buffer{}
+    var err error
+    switch format {
+    case "jpeg":
+        err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 90})
+    case "png":
+        err = png.Encode(buf, img)
+    case "gif":
+        err = gif.Encode(buf, img, nil)
+    default:
+        err = ErrUnsupportedType
+    }
+    return buf.Bytes(), err
+}
+
+// Thumbnail scales an image down so its longest side is at most size,
+// averaging the source pixels under each thumbnail pixel. Smaller images are
+// returned unchanged.
+func Thumbnail(img image.Image, size int) image.Image {
+    bounds := img.Bounds()
+    w, h := bounds.Dx(), bounds.Dy()
+    if w <= size && h <= size {
+        return img
+    }
+    tw, th := size, h*size/w
+    if h > w {
+        tw, th = w*size/h, size
+    }
+    tw, th = max(tw, 1), max(th, 1)
+
+    thumb := image.NewNRGBA(image.Rect(0, 0, tw, th))
+    for ty := 0; ty < th; ty++ {
+        y0 := bounds.Min.Y + ty*h/th
+        y1 := max(bounds.Min.Y+(ty+1)*h/th, y0+1)
+        for tx := 0; tx < tw; tx++ {
+            x0 := bounds.Min.X + tx*w/tw
+            x1 := max(bounds.Min.X+(tx+1)*w/tw, x0+1)
+            var r, g, b, a, n uint64
+            for y := y0; y < y1; y++ {
+                for x := x0; x < x1; x++ {
+                    pr, pg, pb, pa := img.At(x, y).RGBA()
+                    r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+                    n++
+                }
+            }
+            // average premultiplied values, then un-premultiply
+            c := color.NRGBA64{}
+            if a > 0 {
+                c = color.NRGBA64{
+                    R: uint16(r * 0xffff / a),
+                    G: uint16(g * 0xffff / a),
+                    B: uint16(b * 0xffff / a),
+                    A: uint16(a / n),
+                }
+            }
+            thumb.Set(tx, ty, c)
+        }
+    }
+    return thumb
+}
@@ -0,0 +1,89 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files under a directory.
+type LocalStore struct {
+    dir  string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+    dir, err := filepath.Abs(dir)
+    if err != nil {
+        return nil, err
+    }
+    if err = os.MkdirAll(dir, 0o755); err != nil {
+        return nil, err
+    }
+    return &LocalStore{dir: dir}, nil
+}
+
+// path of a key, refusing keys that would escape the directory
+func (s *LocalStore) path(key string) (string, error) {
+    if key == "" || strings.HasPrefix(key, "/") {
+        return "", fmt.Errorf("invalid blob key '%s'", key)
+    }
+    path := filepath.Join(s.dir, filepath.FromSlash(key))
+    if !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+        return "", fmt.Errorf("invalid blob key '%s'", key)
+    }
+    return path, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+
+    // write to a temporary file first so readers never see a partial blob
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err = tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err = tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    path, err := s.path(key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, ErrNotFound
+    }
+    return f, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    err = os.Remove(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,100 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.NRGBA {
+    img := image.NewNRGBA(image.Rect(0, 0, w, h))
+    for y := 0; y < h; y++ {
+        for x := 0; x < w; x++ {
+            img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+        }
+    }
+    return img
+}
+
+// insert an APP1 EXIF segment right after the JPEG start of image marker
+func withExif(data []byte) []byte {
+    payload := append([]byte("Exif\x00\x00"), []byte("GPS 51.5074 N 0.1278 W")...)
+    segment := []byte{0xff, 0xe1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+    segment = append(segment, payload...)
+    out := append([]byte{}, data[:2]...)
+    out = append(out, segment...)
+    return append(out, data[2:]...)
+}
+
+func TestProcessStripsExif(t *testing.T) {
+    buf := &bytes.Buffer{}
+    if err := jpeg.Encode(buf, testImage(640, 480), nil); err != nil {
+        t.Fatalf("error encoding test image: %s", err)
+    }
+    data := withExif(buf.Bytes())
+    if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+        t.Fatalf("test image with EXIF does not decode: %s", err)
+    }
+
+    img, err := Process(data)
+    if err != nil {
+        t.Fatalf("error processing image: %s", err)
+    }
+    if img.ContentType != "image/jpeg" || img.Extension != "jpg" || img.Width != 640 || img.Height != 480 {
+        t.Fatalf("unexpected image %s %s %dx%d", img.ContentType, img.Extension, img.Width, img.Height)
+    }
+    if bytes.Contains(img.Data, []byte("Exif")) || bytes.Contains(img.Data, []byte("GPS")) {
+        t.Fatalf("EXIF metadata was kept")
+    }
+
+    thumb, err := jpeg.DecodeConfig(bytes.NewReader(img.Thumbnail))
+    if err != nil {
+        t.Fatalf("error decoding thumbnail: %s", err)
+    }
+    if thumb.Width != ThumbnailSize || thumb.Height != 240 {
+        t.Fatalf("thumbnail is %dx%d, expected %dx240", thumb.Width, thumb.Height, ThumbnailSize)
+    }
+}
+
+func TestProcessSniffsType(t *testing.T) {
+    buf := &bytes.Buffer{}
+    if err := png.Encode(buf, testImage(10, 20)); err != nil {
+        t.Fatalf("error encoding test image: %s", err)
+    }
+    img, err := Process(buf.Bytes())
+    if err != nil {
+        t.Fatalf("error processing image: %s", err)
+    }
+    if img.ContentType != "image/png" || img.Width != 10 || img.Height != 20 {
+        t.Fatalf("unexpected image %s %dx%d", img.ContentType, img.Width, img.Height)
+    }
+
+    _, err = Process([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"))
+    if !errors.Is(err, ErrUnsupportedType) {
+        t.Fatalf("svg upload returned %v, expected %v", err, ErrUnsupportedType)
+    }
+    _, err = Process(make([]byte, MaxUploadBytes+1))
+    if !errors.Is(err, ErrTooLarge) {
+        t.Fatalf("oversized upload returned %v, expected %v", err, ErrTooLarge)
+    }
+}
+
+func TestThumbnailAverages(t *testing.T) {
+    img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+    for x := 0; x < 4; x++ {
+        img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+        img.Set(x, 1, color.NRGBA{B: 255, A: 255})
+    }
+    thumb := Thumbnail(img, 2)
+    if thumb.Bounds().Dx() != 2 || thumb.Bounds().Dy() != 1 {
+        t.Fatalf("thumbnail is %v, expected 2x1", thumb.Bounds())
+    }
+    c := color.NRGBAModel.Convert(thumb.At(0, 0)).(color.NRGBA)
+    if c.R != 127 || c.B != 127 || c.A != 255 {
+        t.Fatalf("thumbnail pixel is %+v, expected an even mix", c)
+    }
+}
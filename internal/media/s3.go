@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config describes an S3-compatible bucket, addressed path style so that
+// stand-ins such as MinIO work without DNS.
+type S3Config struct {
+    Endpoint   string
+    Region     string
+    Bucket     string
+    AccessKey  string
+    SecretKey  string
+}
+
+// S3Store keeps blobs in an S3-compatible bucket, signing requests with
+// AWS Signature Version 4.
+type S3Store struct {
+    cfg     S3Config
+    base    *url.URL
+    client  *http.Client
+    now     func() time.Time
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+    base, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+    if err != nil || base.Scheme == "" || base.Host == "" {
+        return nil, fmt.Errorf("invalid S3 endpoint '%s'", cfg.Endpoint)
+    }
+    if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+        return nil, fmt.Errorf("S3 bucket and credentials must be set")
+    }
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+    return &S3Store{
+        cfg:    cfg,
+        base:   base,
+        client: &http.Client{Timeout: 30 * time.Second},
+        now:    time.Now,
+    }, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key, contentType string, data []byte) error {
+    resp, err := s.do(ctx, http.MethodPut, key, contentType, data)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return s3Error(resp)
+    }
+    return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    resp, err := s.do(ctx, http.MethodGet, key, "", nil)
+    if err != nil {
+        return nil, err
+    }
+    switch resp.StatusCode {
+    case http.StatusOK:
+        return resp.Body, nil
+    case http.StatusNotFound:
+        resp.Body.Close()
+        return nil, ErrNotFound
+    default:
+        defer resp.Body.Close()
+        return nil, s3Error(resp)
+    }
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+    resp, err := s.do(ctx, http.MethodDelete, key, "", nil)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+        return s3Error(resp)
+    }
+    return nil
+}
+
+// send a signed request for an object
+func (s *S3Store) do(ctx context.Context, method, key, contentType string, data []byte) (*http.Response, error) {
+    u := *s.base
+    u.Path = u.Path + "/" + s.cfg.Bucket + "/" + key
+    req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(data))
+    if err != nil {
+        return nil, err
+    }
+    if contentType != "" {
+        req.Header.Set("Content-Type", contentType)
+    }
+    s.sign(req, data)
+    return s.client.Do(req)
+}
+
+// sign the request in place, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3Store) sign(req *http.Request, payload []byte) {
+    now := s.now().UTC()
+    amzDate := now.Format("20060102T150405Z")
+    date := now.Format("20060102")
+    payloadHash := sha256Hex(payload)
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+    // canonical headers are the host and every header set above, lower cased
+    // and sorted
+    headers := map[string]string{"host": req.URL.Host}
+    for name := range req.Header {
+        headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    canonicalHeaders := ""
+    for _, name := range names {
+        canonicalHeaders += name + ":" + headers[name] + "\n"
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        req.URL.RawQuery,
+        canonicalHeaders,
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+    scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+    stringToSign := strings.Join([]string{
+        "AWS4-HMAC-SHA256",
+        amzDate,
+        scope,
+        sha256Hex([]byte(canonicalRequest)),
+    }, "\n")
+
+    key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+    key = hmacSHA256(key, s.cfg.Region)
+    key = hmacSHA256(key, "s3")
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf(
+        "AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.cfg.AccessKey, scope, signedHeaders, signature,
+    ))
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
+
+func s3Error(resp *http.Response) error {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return fmt.Errorf("S3 responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
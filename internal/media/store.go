@@ -0,0 +1,18 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a blob does not exist in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files by key. Keys are slash separated paths such
+// as "media/<id>.jpg".
+type BlobStore interface {
+    Put(ctx context.Context, key, contentType string, data []byte) error
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Delete(ctx context.Context, key string) error
+}
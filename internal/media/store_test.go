@@ -0,0 +1,131 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+	"testing"
+)
+
+func checkStore(t *testing.T, store BlobStore) {
+    ctx := context.Background()
+    if err := store.Put(ctx, "media/one.png", "image/png", []byte("blob")); err != nil {
+        t.Fatalf("error putting blob: %s", err)
+    }
+    rc, err := store.Get(ctx, "media/one.png")
+    if err != nil {
+        t.Fatalf("error getting blob: %s", err)
+    }
+    data, _ := io.ReadAll(rc)
+    rc.Close()
+    if string(data) != "blob" {
+        t.Fatalf("blob read back as '%s'", data)
+    }
+    if err = store.Delete(ctx, "media/one.png"); err != nil {
+        t.Fatalf("error deleting blob: %s", err)
+    }
+    if _, err = store.Get(ctx, "media/one.png"); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("deleted blob returned %v, expected %v", err, ErrNotFound)
+    }
+    if err = store.Delete(ctx, "media/one.png"); err != nil {
+        t.Fatalf("deleting a missing blob returned %s", err)
+    }
+}
+
+func TestLocalStore(t *testing.T) {
+    store, err := NewLocalStore(t.TempDir())
+    if err != nil {
+        t.Fatalf("error creating store: %s", err)
+    }
+    checkStore(t, store)
+    for _, key := range []string{"../escape", "/etc/passwd", "media/../../escape"} {
+        if err = store.Put(context.Background(), key, "text/plain", nil); err == nil {
+            t.Fatalf("key '%s' was accepted", key)
+        }
+    }
+}
+
+// fakeS3 is a minimal path-style S3 stand-in that checks requests are signed
+type fakeS3 struct {
+    mu       sync.Mutex
+    objects  map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    auth := r.Header.Get("Authorization")
+    if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+        http.Error(w, "missing signature", http.StatusForbidden)
+        return
+    }
+    body, _ := io.ReadAll(r.Body)
+    sum := sha256.Sum256(body)
+    if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+        http.Error(w, "payload hash mismatch", http.StatusBadRequest)
+        return
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    switch r.Method {
+    case http.MethodPut:
+        f.objects[r.URL.Path] = body
+    case http.MethodGet:
+        data, ok := f.objects[r.URL.Path]
+        if !ok {
+            http.Error(w, "NoSuchKey", http.StatusNotFound)
+            return
+        }
+        w.Write(data)
+    case http.MethodDelete:
+        delete(f.objects, r.URL.Path)
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+func TestS3Store(t *testing.T) {
+    fake := &fakeS3{objects: map[string][]byte{}}
+    server := httptest.NewServer(fake)
+    defer server.Close()
+
+    store, err := NewS3Store(S3Config{
+        Endpoint:  server.URL,
+        Region:    "eu-west-1",
+        Bucket:    "chirpy",
+        AccessKey: "key",
+        SecretKey: "secret",
+    })
+    if err != nil {
+        t.Fatalf("error creating store: %s", err)
+    }
+    checkStore(t, store)
+
+    store.Put(context.Background(), "media/two.png", "image/png", []byte("two"))
+    if _, ok := fake.objects["/chirpy/media/two.png"]; !ok {
+        t.Fatalf("object not stored under the bucket path: %v", fake.objects)
+    }
+}
+
+func TestS3SignatureIsDeterministic(t *testing.T) {
+    store, _ := NewS3Store(S3Config{Endpoint: "http://localhost:9000", Bucket: "b", AccessKey: "key", SecretKey: "secret"})
+    sign := func(secret string) string {
+        store.cfg.SecretKey = secret
+        req := httptest.NewRequest(http.MethodGet, "http://localhost:9000/b/media/x.png", nil)
+        store.sign(req, nil)
+        return req.Header.Get("Authorization")
+    }
+    fixed, _ := http.ParseTime("Mon, 02 Jan 2006 15:04:05 GMT")
+    store.now = func() time.Time { return fixed }
+    if sign("secret") != sign("secret") {
+        t.Fatalf("signature changed between identical requests")
+    }
+    if sign("secret") == sign("other") {
+        t.Fatalf("signature does not depend on the secret key")
+    }
+}
@@ -0,0 +1,180 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config is the JSON file describing the moderation pipeline. Word list
+// paths are relative to the config file.
+type Config struct {
+    WordLists   []WordListConfig   `json:"word_lists"`
+    RegexRules  []RegexRuleConfig  `json:"regex_rules"`
+}
+
+type WordListConfig struct {
+    Name    string  `json:"name"`
+    Path    string  `json:"path"`
+    Action  string  `json:"action"`
+}
+
+type RegexRuleConfig struct {
+    Name     string  `json:"name"`
+    Pattern  string  `json:"pattern"`
+    Action   string  `json:"action"`
+}
+
+// LoadPipeline builds a pipeline from a config file and its word lists.
+func LoadPipeline(path string) (*Pipeline, error) {
+    pipeline, _, err := load(path)
+    return pipeline, err
+}
+
+// load the pipeline, also returning every file it was read from
+func load(path string) (*Pipeline, []string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, nil, err
+    }
+    cfg := Config{}
+    if err = json.Unmarshal(data, &cfg); err != nil {
+        return nil, nil, fmt.Errorf("error decoding moderation config: %s", err)
+    }
+
+    files := []string{path}
+    filters := []Filter{}
+    for _, wlc := range cfg.WordLists {
+        action, ok := ParseAction(wlc.Action)
+        if !ok {
+            return nil, nil, fmt.Errorf("word list '%s' has unknown action '%s'", wlc.Name, wlc.Action)
+        }
+        wordPath := wlc.Path
+        if !filepath.IsAbs(wordPath) {
+            wordPath = filepath.Join(filepath.Dir(path), wordPath)
+        }
+        wl, err := readWordList(wlc.Name, wordPath, action)
+        if err != nil {
+            return nil, nil, err
+        }
+        files = append(files, wordPath)
+        filters = append(filters, wl)
+    }
+    for _, rrc := range cfg.RegexRules {
+        action, ok := ParseAction(rrc.Action)
+        if !ok {
+            return nil, nil, fmt.Errorf("regex rule '%s' has unknown action '%s'", rrc.Name, rrc.Action)
+        }
+        rr, err := NewRegexRule(rrc.Name, action, rrc.Pattern)
+        if err != nil {
+            return nil, nil, fmt.Errorf("regex rule '%s' is invalid: %s", rrc.Name, err)
+        }
+        filters = append(filters, rr)
+    }
+    return NewPipeline(filters...), files, nil
+}
+
+// read a word list with one word per line, each optionally followed by its
+// own action, and '#' starting a comment
+func readWordList(name, path string, action Action) (*WordList, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    wl := NewWordList(name, action, nil)
+    scanner := bufio.NewScanner(f)
+    for line := 1; scanner.Scan(); line++ {
+        text, _, _ := strings.Cut(scanner.Text(), "#")
+        fields := strings.Fields(text)
+        switch len(fields) {
+        case 0:
+            continue
+        case 1:
+            wl.Add(fields[0], action)
+        case 2:
+            wordAction, ok := ParseAction(fields[1])
+            if !ok {
+                return nil, fmt.Errorf("%s:%d: unknown action '%s'", path, line, fields[1])
+            }
+            wl.Add(fields[0], wordAction)
+        default:
+            return nil, fmt.Errorf("%s:%d: expected a word and an optional action", path, line)
+        }
+    }
+    return wl, scanner.Err()
+}
+
+// Moderator is a pipeline that can be replaced while chirps are being
+// moderated.
+type Moderator struct {
+    pipeline  atomic.Pointer[Pipeline]
+}
+
+func NewModerator(pipeline *Pipeline) *Moderator {
+    m := &Moderator{}
+    m.pipeline.Store(pipeline)
+    return m
+}
+
+func (m *Moderator) Moderate(body string) Result {
+    return m.pipeline.Load().Moderate(body)
+}
+
+func (m *Moderator) Swap(pipeline *Pipeline) {
+    m.pipeline.Store(pipeline)
+}
+
+// WatchConfig polls the config file and its word lists, reloading the
+// pipeline when any of them changes. A broken config is logged and the
+// previous pipeline is kept.
+func (m *Moderator) WatchConfig(ctx context.Context, path string, interval time.Duration) {
+    _, files, err := load(path)
+    if err != nil {
+        files = []string{path}
+    }
+    stamp := fingerprint(files)
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+        if current := fingerprint(files); current == stamp {
+            continue
+        }
+        pipeline, newFiles, err := load(path)
+        if err != nil {
+            log.Printf("error reloading moderation config, keeping the previous rules: %s", err)
+            stamp = fingerprint(files)
+            continue
+        }
+        m.Swap(pipeline)
+        files = newFiles
+        stamp = fingerprint(files)
+        log.Printf("reloaded moderation config from %s", path)
+    }
+}
+
+// summarise the size and modification time of each file
+func fingerprint(files []string) string {
+    var b strings.Builder
+    for _, file := range files {
+        info, err := os.Stat(file)
+        if err != nil {
+            fmt.Fprintf(&b, "%s:missing;", file)
+            continue
+        }
+        fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+    }
+    return b.String()
+}
@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordList matches whole words against a list, ignoring case, accents,
+// surrounding punctuation and invisible characters.
+type WordList struct {
+    name    string
+    action  Action
+    words   map[string]Action
+}
+
+// NewWordList builds a word list where every word uses the list's action.
+func NewWordList(name string, action Action, words []string) *WordList {
+    wl := &WordList{name: name, action: action, words: map[string]Action{}}
+    for _, word := range words {
+        wl.Add(word, action)
+    }
+    return wl
+}
+
+// Add a word with its own action, overriding the list's action.
+func (wl *WordList) Add(word string, action Action) {
+    if key := normalize(word); key != "" {
+        wl.words[key] = action
+    }
+}
+
+func (wl *WordList) Match(body string) []Match {
+    matches := []Match{}
+    start := -1
+    for i, r := range body + " " {
+        if isWordRune(r) {
+            if start < 0 {
+                start = i
+            }
+            continue
+        }
+        if start >= 0 {
+            if action, ok := wl.words[normalize(body[start:i])]; ok {
+                matches = append(matches, Match{Rule: wl.name, Action: action, Start: start, End: i})
+            }
+            start = -1
+        }
+    }
+    return matches
+}
+
+// RegexRule matches a regular expression against the raw chirp body.
+type RegexRule struct {
+    name     string
+    action   Action
+    pattern  *regexp.Regexp
+}
+
+func NewRegexRule(name string, action Action, pattern string) (*RegexRule, error) {
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        return nil, err
+    }
+    return &RegexRule{name: name, action: action, pattern: re}, nil
+}
+
+func (rr *RegexRule) Match(body string) []Match {
+    matches := []Match{}
+    for _, loc := range rr.pattern.FindAllStringIndex(body, -1) {
+        matches = append(matches, Match{Rule: rr.name, Action: rr.action, Start: loc[0], End: loc[1]})
+    }
+    return matches
+}
+
+// letters, digits, combining marks and zero-width characters are part of a
+// word, so "kerf\u200buffle" is one word while "Kerfuffle!" ends at the '!'
+func isWordRune(r rune) bool {
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r)
+}
+
+// fold a word to lowercase, with compatibility forms, accents and zero-width
+// characters removed, so "ＫＥＲＦＵＦＦＬＥ" and "kérfuffle" match "kerfuffle"
+func normalize(word string) string {
+    var b strings.Builder
+    for _, r := range norm.NFKD.String(word) {
+        if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) || unicode.IsSpace(r) {
+            continue
+        }
+        b.WriteRune(unicode.ToLower(r))
+    }
+    return b.String()
+}
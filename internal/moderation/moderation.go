@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"sort"
+	"strings"
+)
+
+// Action is what happens to a chirp when a rule matches it.
+type Action string
+
+const (
+    ActionMask    Action = "mask"
+    ActionHold    Action = "hold"
+    ActionReject  Action = "reject"
+)
+
+// severity orders actions, so the strongest triggered action wins
+var severity = map[Action]int{
+    "":            0,
+    ActionMask:    1,
+    ActionHold:    2,
+    ActionReject:  3,
+}
+
+func ParseAction(s string) (Action, bool) {
+    action := Action(strings.ToLower(strings.TrimSpace(s)))
+    _, ok := severity[action]
+    return action, ok && action != ""
+}
+
+// Match is a rule hit in a chirp body, with byte offsets [Start, End).
+type Match struct {
+    Rule    string
+    Action  Action
+    Start   int
+    End     int
+}
+
+// Filter finds the rule hits in a chirp body.
+type Filter interface {
+    Match(body string) []Match
+}
+
+// Result is the outcome of moderating a chirp body.
+type Result struct {
+    Body    string
+    Action  Action
+    Rules   []string
+}
+
+// states a chirp is stored in. Only visible chirps are listed, held chirps
+// wait for a moderator and hidden chirps were taken down by one.
+const (
+    StateVisible  = "visible"
+    StateHeld     = "held"
+    StateHidden   = "hidden"
+)
+
+// strictness orders states, so an edit cannot relax one
+var strictness = map[string]int{
+    StateVisible:  0,
+    StateHeld:     1,
+    StateHidden:   2,
+}
+
+// State is the state a new chirp with this result is stored in.
+func (r Result) State() string {
+    if r.Action == ActionHold {
+        return StateHeld
+    }
+    return StateVisible
+}
+
+// EditedState is the state of a chirp in the current state after an edit
+// with this result. The stricter state is kept, so editing neither releases a
+// held chirp without review nor brings back a hidden one.
+func (r Result) EditedState(current string) string {
+    state := r.State()
+    if strictness[current] > strictness[state] {
+        return current
+    }
+    return state
+}
+
+// Pipeline runs every filter over a chirp body and combines their hits.
+type Pipeline struct {
+    filters  []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+    return &Pipeline{filters: filters}
+}
+
+func (p *Pipeline) Moderate(body string) Result {
+    result := Result{Body: body, Rules: []string{}}
+    masks := []Match{}
+    seen := map[string]struct{}{}
+    for _, filter := range p.filters {
+        for _, match := range filter.Match(body) {
+            if severity[match.Action] > severity[result.Action] {
+                result.Action = match.Action
+            }
+            if _, ok := seen[match.Rule]; !ok {
+                seen[match.Rule] = struct{}{}
+                result.Rules = append(result.Rules, match.Rule)
+            }
+            if match.Action == ActionMask {
+                masks = append(masks, match)
+            }
+        }
+    }
+    result.Body = mask(body, masks)
+    return result
+}
+
+// replace each masked span with asterisks, merging overlapping spans
+func mask(body string, matches []Match) string {
+    if len(matches) == 0 {
+        return body
+    }
+    sort.Slice(matches, func(i, j int) bool {
+        return matches[i].Start < matches[j].Start
+    })
+    var b strings.Builder
+    last := 0
+    for _, m := range matches {
+        if m.End <= last {
+            continue
+        }
+        if m.Start >= last {
+            b.WriteString(body[last:m.Start])
+            b.WriteString("****")
+        }
+        last = m.End
+    }
+    b.WriteString(body[last:])
+    return b.String()
+}
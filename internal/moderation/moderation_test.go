@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWordListNormalizedMatching(t *testing.T) {
+    p := NewPipeline(NewWordList("profanity", ActionMask, []string{"kerfuffle", "fornax"}))
+    result := p.Moderate("What a Kerfuffle! ＦＯＲＮＡＸ, kérfuffle and ker\u200bfuffle")
+    want := "What a ****! ****, **** and ****"
+    if result.Body != want {
+        t.Fatalf("body masked as '%s', expected '%s'", result.Body, want)
+    }
+    if result.Action != ActionMask || !reflect.DeepEqual(result.Rules, []string{"profanity"}) {
+        t.Fatalf("unexpected result %+v", result)
+    }
+}
+
+func TestWordListWholeWords(t *testing.T) {
+    p := NewPipeline(NewWordList("profanity", ActionMask, []string{"fornax"}))
+    result := p.Moderate("fornaxes are fine")
+    if result.Body != "fornaxes are fine" || result.Action != "" || len(result.Rules) != 0 {
+        t.Fatalf("partial word was moderated: %+v", result)
+    }
+}
+
+func TestStrongestActionWins(t *testing.T) {
+    rr, err := NewRegexRule("phone", ActionHold, `\d{3}-\d{4}`)
+    if err != nil {
+        t.Fatalf("error compiling rule: %s", err)
+    }
+    wl := NewWordList("profanity", ActionMask, []string{"sharbert"})
+    wl.Add("spam", ActionReject)
+    p := NewPipeline(wl, rr)
+
+    result := p.Moderate("sharbert call 555-1234")
+    if result.Action != ActionHold || result.Body != "**** call 555-1234" {
+        t.Fatalf("unexpected result %+v", result)
+    }
+    if !reflect.DeepEqual(result.Rules, []string{"profanity", "phone"}) {
+        t.Fatalf("rules recorded as %v", result.Rules)
+    }
+    if result = p.Moderate("buy SPAM now"); result.Action != ActionReject {
+        t.Fatalf("expected word action override to reject, got %+v", result)
+    }
+}
+
+func TestLoadPipeline(t *testing.T) {
+    dir := t.TempDir()
+    words := "# masked words\nkerfuffle\nscam reject\n"
+    if err := os.WriteFile(filepath.Join(dir, "words.txt"), []byte(words), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    config := `{
+        "word_lists": [{"name": "words", "path": "words.txt", "action": "mask"}],
+        "regex_rules": [{"name": "links", "pattern": "(?i)https?://", "action": "hold"}]
+    }`
+    path := filepath.Join(dir, "moderation.json")
+    if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    p, err := LoadPipeline(path)
+    if err != nil {
+        t.Fatalf("error loading pipeline: %s", err)
+    }
+    if result := p.Moderate("a kerfuffle at HTTP://example.com"); result.Action != ActionHold {
+        t.Fatalf("expected hold, got %+v", result)
+    }
+    if result := p.Moderate("total scam"); result.Action != ActionReject {
+        t.Fatalf("expected reject, got %+v", result)
+    }
+}
+
+func TestEditedStateNeverRelaxes(t *testing.T) {
+    clean := Result{Body: "fine now"}
+    held := Result{Body: "still iffy", Action: ActionHold}
+    cases := []struct {
+        current   string
+        result    Result
+        expected  string
+    }{
+        {StateVisible, clean, StateVisible},
+        {StateVisible, held, StateHeld},
+        {StateHeld, clean, StateHeld},
+        {StateHidden, clean, StateHidden},
+        {StateHidden, held, StateHidden},
+    }
+    for _, c := range cases {
+        if got := c.result.EditedState(c.current); got != c.expected {
+            t.Errorf("editing a %s chirp with action '%s' gave %s, expected %s", c.current, c.result.Action, got, c.expected)
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package trends
+
+import (
+	"math"
+	"sort"
+)
+
+// Count is the number of uses of a tag in the recent window, and in the
+// baseline period before it.
+type Count struct {
+    Tag       string
+    Recent    int64
+    Baseline  int64
+}
+
+type Trend struct {
+    Tag       string   `json:"tag"`
+    Count     int64    `json:"count"`
+    Expected  float64  `json:"expected"`
+    Score     float64  `json:"score"`
+}
+
+// Rank scores each tag by how far its recent count is above the count
+// expected from the baseline, where periods is how many recent windows fit in
+// the baseline period. Tags with fewer than minCount recent uses or no
+// acceleration are dropped, and at most limit trends are returned.
+func Rank(counts []Count, periods float64, minCount int64, limit int) []Trend {
+    trends := []Trend{}
+    for _, c := range counts {
+        if c.Recent < minCount {
+            continue
+        }
+        expected := float64(c.Baseline) / periods
+        // treat the counts as Poisson, so the excess is scaled by the
+        // expected spread and a new tag is not infinitely accelerating
+        score := (float64(c.Recent) - expected) / math.Sqrt(expected+1)
+        if score <= 0 {
+            continue
+        }
+        trends = append(trends, Trend{Tag: c.Tag, Count: c.Recent, Expected: expected, Score: score})
+    }
+    sort.Slice(trends, func(i, j int) bool {
+        if trends[i].Score != trends[j].Score {
+            return trends[i].Score > trends[j].Score
+        }
+        return trends[i].Tag < trends[j].Tag
+    })
+    if len(trends) > limit {
+        trends = trends[:limit]
+    }
+    return trends
+}
@@ -0,0 +1,32 @@
+package trends
+
+import (
+	"testing"
+)
+
+func TestRankAcceleration(t *testing.T) {
+    counts := []Count{
+        {Tag: "steady", Recent: 10, Baseline: 240},
+        {Tag: "rising", Recent: 10, Baseline: 24},
+        {Tag: "new", Recent: 5, Baseline: 0},
+    }
+    trends := Rank(counts, 24, 3, 10)
+    if len(trends) != 2 {
+        t.Fatalf("expected 2 trends, got %v", trends)
+    }
+    if trends[0].Tag != "rising" || trends[1].Tag != "new" {
+        t.Fatalf("trends ranked as %v, expected rising then new", trends)
+    }
+}
+
+func TestRankMinCountAndLimit(t *testing.T) {
+    counts := []Count{
+        {Tag: "a", Recent: 9, Baseline: 0},
+        {Tag: "b", Recent: 8, Baseline: 0},
+        {Tag: "c", Recent: 2, Baseline: 0},
+    }
+    trends := Rank(counts, 7, 3, 1)
+    if len(trends) != 1 || trends[0].Tag != "a" {
+        t.Fatalf("expected only the top trend, got %v", trends)
+    }
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// failed deliveries are retried until this many attempts have been made,
+// after which they are dead
+const MaxAttempts = 8
+
+const (
+    baseDelay  = 30 * time.Second
+    maxDelay   = 6 * time.Hour
+)
+
+// Backoff is how long to wait after a delivery has failed the given number of
+// times, doubling from 30 seconds up to 6 hours.
+func Backoff(failures int) time.Duration {
+    delay := baseDelay
+    for i := 1; i < failures && delay < maxDelay; i++ {
+        delay *= 2
+    }
+    return min(delay, maxDelay)
+}
+
+// Request is one attempt to deliver an event to a webhook.
+type Request struct {
+    WebhookID  string
+    EventID    string
+    EventType  string
+    URL        string
+    Secret     string
+    Body       []byte
+}
+
+// Client posts signed deliveries to webhook URLs.
+type Client struct {
+    http  *http.Client
+}
+
+// NewClient makes a client whose requests time out after the given duration.
+// Unless allowPrivate is set, it refuses to connect to loopback, private and
+// link-local addresses, so webhooks cannot be used to reach internal
+// services. The check is made on the address dialled, after DNS.
+func NewClient(timeout time.Duration, allowPrivate bool) *Client {
+    dialer := &net.Dialer{Timeout: timeout}
+    if !allowPrivate {
+        dialer.Control = refusePrivate
+    }
+    transport := &http.Transport{
+        // a proxy would dial on our behalf and skip the address check
+        Proxy:               nil,
+        DialContext:         dialer.DialContext,
+        TLSHandshakeTimeout: timeout,
+        MaxIdleConnsPerHost: 2,
+        IdleConnTimeout:     90 * time.Second,
+    }
+    return &Client{http: &http.Client{
+        Timeout:   timeout,
+        Transport: transport,
+        // a redirect counts as a failure rather than being followed somewhere
+        // that was never registered
+        CheckRedirect: func(*http.Request, []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }}
+}
+
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return err
+    }
+    ip := net.ParseIP(host)
+    if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+        ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+        return fmt.Errorf("refusing to connect to %s", host)
+    }
+    return nil
+}
+
+// Deliver posts a signed request and returns the status code of the response,
+// or 0 if there was none. Any status outside 2xx is an error.
+func (c *Client) Deliver(ctx context.Context, req Request) (int, error) {
+    httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+    if err != nil {
+        return 0, err
+    }
+    httpReq.Header.Set("Content-Type", "application/json")
+    httpReq.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+    httpReq.Header.Set(HeaderWebhookID, req.WebhookID)
+    httpReq.Header.Set(HeaderEventID, req.EventID)
+    httpReq.Header.Set(HeaderEventType, req.EventType)
+    now := time.Now()
+    httpReq.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+    httpReq.Header.Set(HeaderSignature, Sign(req.Secret, now, req.Body))
+
+    resp, err := c.http.Do(httpReq)
+    if err != nil {
+        return 0, err
+    }
+    defer resp.Body.Close()
+    // read a little of the body so the connection can be reused
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return resp.StatusCode, errors.New(resp.Status)
+    }
+    return resp.StatusCode, nil
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// headers sent with every delivery
+const (
+    HeaderWebhookID  = "Chirpy-Webhook-Id"
+    HeaderEventID    = "Chirpy-Event-Id"
+    HeaderEventType  = "Chirpy-Event-Type"
+    HeaderTimestamp  = "Chirpy-Timestamp"
+    HeaderSignature  = "Chirpy-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// NewSecret makes a random signing secret for a webhook.
+func NewSecret() (string, error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature of a body sent at a time, an HMAC-SHA256 of the
+// Unix timestamp, a dot and the body. Signing the timestamp stops a captured
+// delivery being replayed with a new one.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+    mac.Write([]byte("."))
+    mac.Write(body)
+    return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a delivery, and that it was sent
+// within tolerance of now. Receivers do this with their copy of the secret.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+    unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+    if err != nil {
+        return fmt.Errorf("invalid timestamp: %w", err)
+    }
+    timestamp := time.Unix(unix, 0)
+    if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+        return errors.New("timestamp outside tolerance")
+    }
+    expected := Sign(secret, timestamp, body)
+    if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(expected)) {
+        return errors.New("invalid signature")
+    }
+    return nil
+}
+
+// ValidateURL checks a webhook URL is an absolute HTTP or HTTPS URL.
+func ValidateURL(raw string) error {
+    u, err := url.Parse(raw)
+    if err != nil {
+        return err
+    }
+    if u.Scheme != "http" && u.Scheme != "https" {
+        return errors.New("URL must use http or https")
+    }
+    if u.Hostname() == "" {
+        return errors.New("URL has no host")
+    }
+    if u.User != nil {
+        return errors.New("URL must not contain credentials")
+    }
+    return nil
+}
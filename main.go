@@ -1,24 +1,51 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/events"
+	"github.com/CraigYanitski/server-test/internal/mail"
+	"github.com/CraigYanitski/server-test/internal/media"
+	"github.com/CraigYanitski/server-test/internal/moderation"
+	"github.com/CraigYanitski/server-test/internal/webhook"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-    fileserverHits  atomic.Int32
-    dbQueries       *database.Queries
-    platform        string
-    secret          string
-    polkaKey        string
+    fileserverHits      atomic.Int32
+    db                  *sql.DB
+    dbQueries           *database.Queries
+    platform            string
+    secret              string
+    polkaKey            string
+    moderator           *moderation.Moderator
+    editWindow          time.Duration
+    restoreWindow       time.Duration
+    retention           time.Duration
+    maxChirpLength      int
+    maxChirpLengthRed   int
+    trends              trendCache
+    blobs               media.BlobStore
+    events              *events.Broker
+    live                *connTracker
+    mailer              mail.Mailer
+    mailFrom            string
+    baseURL             string
+    webhooks            *webhook.Client
 }
 
 func main() {
@@ -40,19 +67,63 @@ func main() {
     if polkaKey == "" {
         log.Fatal("POLKA_KEY must be set")
     }
+    editWindow := envDuration("CHIRP_EDIT_WINDOW", 15*time.Minute)
+    restoreWindow := envDuration("CHIRP_RESTORE_WINDOW", 7*24*time.Hour)
+    retention := envDuration("CHIRP_RETENTION", 30*24*time.Hour)
+    if restoreWindow > retention {
+        log.Fatal("CHIRP_RESTORE_WINDOW cannot be longer than CHIRP_RETENTION")
+    }
+    maxChirpLength := envInt("CHIRP_MAX_LENGTH", 140)
+    maxChirpLengthRed := envInt("CHIRP_MAX_LENGTH_RED", 280)
+    shutdownTimeout := envDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
     db, err := sql.Open("postgres", dbURL)
     if err != nil {
         log.Fatalf("error opening database: %s", err)
     }
     dbQueries := database.New(db)
+    moderator, err := newModerator(context.Background(), os.Getenv("MODERATION_CONFIG"))
+    if err != nil {
+        log.Fatalf("error loading moderation config: %s", err)
+    }
+    blobs, err := newBlobStore()
+    if err != nil {
+        log.Fatalf("error opening media store: %s", err)
+    }
+    mailer, err := newMailer()
+    if err != nil {
+        log.Fatalf("error setting up mailer: %s", err)
+    }
+    mailFrom := os.Getenv("MAIL_FROM")
+    if mailFrom == "" {
+        mailFrom = "Chirpy <no-reply@localhost>"
+    }
+    baseURL := os.Getenv("BASE_URL")
+    if baseURL == "" {
+        baseURL = "http://localhost:8080"
+    }
 
     // Create API config with DB queries
     apiCfg := apiConfig{
-        fileserverHits: atomic.Int32{},
-        dbQueries:      dbQueries,
-        platform:       platform,
-        secret:         secret,
-        polkaKey:       polkaKey,
+        fileserverHits:     atomic.Int32{},
+        db:                 db,
+        dbQueries:          dbQueries,
+        platform:           platform,
+        secret:             secret,
+        polkaKey:           polkaKey,
+        moderator:          moderator,
+        editWindow:         editWindow,
+        restoreWindow:      restoreWindow,
+        retention:          retention,
+        maxChirpLength:     maxChirpLength,
+        maxChirpLengthRed:  maxChirpLengthRed,
+        blobs:              blobs,
+        events:             events.NewBroker(streamReplaySize, streamPending),
+        live:               newConnTracker(),
+        mailer:             mailer,
+        mailFrom:           mailFrom,
+        baseURL:            strings.TrimSuffix(baseURL, "/"),
+        // webhooks can only reach local receivers in development
+        webhooks:           webhook.NewClient(webhookTimeout, platform == "dev"),
     }
 
     // Initialise multiplexer
@@ -79,9 +150,55 @@ func main() {
     // API users
     mux.HandleFunc("POST /api/users", http.HandlerFunc(apiCfg.handlerCreateUser))
     mux.HandleFunc("PUT /api/users", http.HandlerFunc(apiCfg.handlerUpdateUser))
+    mux.HandleFunc("PATCH /api/users/me", http.HandlerFunc(apiCfg.handlerPatchUser))
     mux.HandleFunc("POST /api/login", http.HandlerFunc(apiCfg.handlerLogin))
     mux.HandleFunc("POST /api/refresh", http.HandlerFunc(apiCfg.handlerRefresh))
     mux.HandleFunc("POST /api/revoke", http.HandlerFunc(apiCfg.handlerRevoke))
+    mux.HandleFunc("GET /api/users/{handle_or_id}", http.HandlerFunc(apiCfg.handlerGetProfile))
+    mux.HandleFunc("PUT /api/users/me/profile", http.HandlerFunc(apiCfg.handlerUpdateProfile))
+    mux.HandleFunc("GET /api/users/{id}/likes", http.HandlerFunc(apiCfg.handlerGetUserLikes))
+    mux.HandleFunc("GET /api/users/{id}/mentions", http.HandlerFunc(apiCfg.handlerGetUserMentions))
+    mux.HandleFunc("POST /api/users/{id}/block", http.HandlerFunc(apiCfg.handlerBlockUser))
+    mux.HandleFunc("DELETE /api/users/{id}/block", http.HandlerFunc(apiCfg.handlerUnblockUser))
+    mux.HandleFunc("POST /api/users/{id}/mute", http.HandlerFunc(apiCfg.handlerMuteUser))
+    mux.HandleFunc("DELETE /api/users/{id}/mute", http.HandlerFunc(apiCfg.handlerUnmuteUser))
+    mux.HandleFunc("POST /api/users/{id}/follow", http.HandlerFunc(apiCfg.handlerFollowUser))
+    mux.HandleFunc("DELETE /api/users/{id}/follow", http.HandlerFunc(apiCfg.handlerUnfollowUser))
+    mux.HandleFunc("GET /api/notifications", http.HandlerFunc(apiCfg.handlerGetNotifications))
+    mux.HandleFunc("POST /api/notifications/{notification_id}/read", http.HandlerFunc(apiCfg.handlerReadNotification))
+    mux.HandleFunc("POST /api/notifications/read-all", http.HandlerFunc(apiCfg.handlerReadAllNotifications))
+    mux.HandleFunc("GET /api/notifications/preferences", http.HandlerFunc(apiCfg.handlerGetNotificationPreferences))
+    mux.HandleFunc("PUT /api/notifications/preferences", http.HandlerFunc(apiCfg.handlerUpdateNotificationPreferences))
+    mux.HandleFunc("GET /api/notifications/digest", http.HandlerFunc(apiCfg.handlerGetDigestSettings))
+    mux.HandleFunc("PUT /api/notifications/digest", http.HandlerFunc(apiCfg.handlerUpdateDigestSettings))
+    mux.HandleFunc("GET /api/notifications/digest/unsubscribe", http.HandlerFunc(apiCfg.handlerUnsubscribePage))
+    mux.HandleFunc("POST /api/notifications/digest/unsubscribe", http.HandlerFunc(apiCfg.handlerUnsubscribe))
+    mux.HandleFunc("GET /api/blocks", http.HandlerFunc(apiCfg.handlerGetBlocks))
+    mux.HandleFunc("GET /api/mutes", http.HandlerFunc(apiCfg.handlerGetMutes))
+
+    // API conversations
+    mux.HandleFunc("POST /api/conversations", http.HandlerFunc(apiCfg.handlerCreateConversation))
+    mux.HandleFunc("GET /api/conversations", http.HandlerFunc(apiCfg.handlerGetConversations))
+    mux.HandleFunc("GET /api/conversations/{conversation_id}", http.HandlerFunc(apiCfg.handlerGetConversation))
+    mux.HandleFunc("POST /api/conversations/{conversation_id}/messages", http.HandlerFunc(apiCfg.handlerSendMessage))
+    mux.HandleFunc("GET /api/conversations/{conversation_id}/messages", http.HandlerFunc(apiCfg.handlerGetMessages))
+    mux.HandleFunc("POST /api/conversations/{conversation_id}/read", http.HandlerFunc(apiCfg.handlerReadConversation))
+    mux.HandleFunc("POST /api/conversations/{conversation_id}/leave", http.HandlerFunc(apiCfg.handlerLeaveConversation))
+    mux.HandleFunc("POST /api/conversations/{conversation_id}/mute", http.HandlerFunc(apiCfg.handlerMuteConversation))
+    mux.HandleFunc("DELETE /api/conversations/{conversation_id}/mute", http.HandlerFunc(apiCfg.handlerUnmuteConversation))
+
+    // API event stream
+    mux.HandleFunc("GET /api/stream", http.HandlerFunc(apiCfg.handlerStream))
+    mux.HandleFunc("GET /api/socket", http.HandlerFunc(apiCfg.handlerSocket))
+
+    // Outbound webhooks
+    mux.HandleFunc("POST /api/webhooks", http.HandlerFunc(apiCfg.handlerCreateWebhook))
+    mux.HandleFunc("GET /api/webhooks", http.HandlerFunc(apiCfg.handlerGetWebhooks))
+    mux.HandleFunc("GET /api/webhooks/{webhook_id}", http.HandlerFunc(apiCfg.handlerGetWebhook))
+    mux.HandleFunc("PUT /api/webhooks/{webhook_id}", http.HandlerFunc(apiCfg.handlerUpdateWebhook))
+    mux.HandleFunc("DELETE /api/webhooks/{webhook_id}", http.HandlerFunc(apiCfg.handlerDeleteWebhook))
+    mux.HandleFunc("GET /api/webhooks/{webhook_id}/deliveries", http.HandlerFunc(apiCfg.handlerGetWebhookDeliveries))
+    mux.HandleFunc("POST /api/webhooks/{webhook_id}/deliveries/{delivery_id}/retry", http.HandlerFunc(apiCfg.handlerRetryWebhookDelivery))
 
     // Polka webhook
     mux.HandleFunc("POST /api/polka/webhooks", http.HandlerFunc(apiCfg.handlerUpgradeUserToRed))
@@ -89,14 +206,127 @@ func main() {
     // API chirps
     mux.HandleFunc("POST /api/chirps", http.HandlerFunc(apiCfg.handlerCreateChirp))
     mux.HandleFunc("GET /api/chirps", http.HandlerFunc(apiCfg.handlerGetChirps))
+    mux.HandleFunc("GET /api/chirps/{chirp_id}", http.HandlerFunc(apiCfg.handlerGetChirp))
+    mux.HandleFunc("PUT /api/chirps/{chirp_id}", http.HandlerFunc(apiCfg.handlerEditChirp))
     mux.HandleFunc("DELETE /api/chirps/{chirp_id}", http.HandlerFunc(apiCfg.handlerDeleteChirp))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/restore", http.HandlerFunc(apiCfg.handlerRestoreChirp))
+    mux.HandleFunc("GET /api/chirps/{chirp_id}/history", http.HandlerFunc(apiCfg.handlerGetChirpHistory))
+    mux.HandleFunc("GET /api/chirps/{chirp_id}/thread", http.HandlerFunc(apiCfg.handlerGetChirpThread))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/like", http.HandlerFunc(apiCfg.handlerLikeChirp))
+    mux.HandleFunc("DELETE /api/chirps/{chirp_id}/like", http.HandlerFunc(apiCfg.handlerUnlikeChirp))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/poll/votes", http.HandlerFunc(apiCfg.handlerVotePoll))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/bookmark", http.HandlerFunc(apiCfg.handlerBookmarkChirp))
+    mux.HandleFunc("DELETE /api/chirps/{chirp_id}/bookmark", http.HandlerFunc(apiCfg.handlerUnbookmarkChirp))
+    mux.HandleFunc("GET /api/bookmarks", http.HandlerFunc(apiCfg.handlerGetBookmarks))
+    mux.HandleFunc("POST /api/bookmarks/collections", http.HandlerFunc(apiCfg.handlerCreateCollection))
+    mux.HandleFunc("GET /api/bookmarks/collections", http.HandlerFunc(apiCfg.handlerGetCollections))
+    mux.HandleFunc("PUT /api/bookmarks/collections/{collection_id}", http.HandlerFunc(apiCfg.handlerRenameCollection))
+    mux.HandleFunc("DELETE /api/bookmarks/collections/{collection_id}", http.HandlerFunc(apiCfg.handlerDeleteCollection))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/pin", http.HandlerFunc(apiCfg.handlerPinChirp))
+    mux.HandleFunc("DELETE /api/chirps/{chirp_id}/pin", http.HandlerFunc(apiCfg.handlerUnpinChirp))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/rechirp", http.HandlerFunc(apiCfg.handlerRechirp))
+    mux.HandleFunc("DELETE /api/chirps/{chirp_id}/rechirp", http.HandlerFunc(apiCfg.handlerUndoRechirp))
+    mux.HandleFunc("GET /api/hashtags/{tag}/chirps", http.HandlerFunc(apiCfg.handlerGetHashtagChirps))
+    mux.HandleFunc("GET /api/timeline", http.HandlerFunc(apiCfg.handlerGetTimeline))
+    mux.HandleFunc("GET /api/trends", http.HandlerFunc(apiCfg.handlerGetTrends))
+    mux.HandleFunc("POST /api/drafts", http.HandlerFunc(apiCfg.handlerCreateDraft))
+    mux.HandleFunc("GET /api/drafts", http.HandlerFunc(apiCfg.handlerGetDrafts))
+    mux.HandleFunc("GET /api/drafts/{draft_id}", http.HandlerFunc(apiCfg.handlerGetDraft))
+    mux.HandleFunc("PUT /api/drafts/{draft_id}", http.HandlerFunc(apiCfg.handlerUpdateDraft))
+    mux.HandleFunc("DELETE /api/drafts/{draft_id}", http.HandlerFunc(apiCfg.handlerDeleteDraft))
+    mux.HandleFunc("POST /api/media", http.HandlerFunc(apiCfg.handlerUploadMedia))
+    mux.HandleFunc("GET /api/media/{media_id}", http.HandlerFunc(apiCfg.handlerGetMedia))
+    mux.HandleFunc("GET /api/media/{media_id}/thumbnail", http.HandlerFunc(apiCfg.handlerGetMediaThumbnail))
+    mux.HandleFunc("POST /api/chirps/{chirp_id}/reports", http.HandlerFunc(apiCfg.handlerCreateReport))
+    mux.HandleFunc("GET /api/reports", http.HandlerFunc(apiCfg.handlerGetMyReports))
+
+    // moderation
+    mux.HandleFunc("GET /api/moderation/reports", http.HandlerFunc(apiCfg.handlerGetReportQueue))
+    mux.HandleFunc("GET /api/moderation/reports/{report_id}", http.HandlerFunc(apiCfg.handlerGetReportContext))
+    mux.HandleFunc("POST /api/moderation/reports/{report_id}/resolve", http.HandlerFunc(apiCfg.handlerResolveReport))
+    mux.HandleFunc("GET /api/moderation/held", http.HandlerFunc(apiCfg.handlerGetHeldChirps))
+    mux.HandleFunc("POST /api/moderation/chirps/{chirp_id}/{action}", http.HandlerFunc(apiCfg.handlerReviewChirp))
+    mux.HandleFunc("GET /api/admin/chirps/deleted", http.HandlerFunc(apiCfg.handlerGetDeletedChirps))
+    mux.HandleFunc("GET /api/admin/chirps/{chirp_id}", http.HandlerFunc(apiCfg.handlerAdminGetChirp))
+    mux.HandleFunc("POST /api/admin/users/{id}/suspension", http.HandlerFunc(apiCfg.handlerSuspendUser))
+    mux.HandleFunc("DELETE /api/admin/users/{id}/suspension", http.HandlerFunc(apiCfg.handlerUnsuspendUser))
     
     // Admin stuff
     mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
     mux.HandleFunc("POST /admin/reset", http.HandlerFunc(apiCfg.handlerReset))
+    mux.HandleFunc("PUT /admin/users/{id}/role", http.HandlerFunc(apiCfg.handlerSetUserRole))
+
+    // bootstrap the first admin, who can then give out roles
+    if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+        promoted, err := apiCfg.dbQueries.PromoteAdminByEmail(context.Background(), adminEmail)
+        if err != nil {
+            log.Fatalf("error promoting admin: %s", err)
+        }
+        if promoted > 0 {
+            log.Printf("made %s an admin", adminEmail)
+        }
+    }
+
+    // Start background jobs, which stop on SIGINT or SIGTERM
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    go apiCfg.runTrendAggregator(ctx, trendInterval)
+    go apiCfg.runScheduler(ctx, schedulerInterval)
+    go apiCfg.runPurger(ctx, purgeInterval)
+    go apiCfg.runDigests(ctx, digestInterval)
+    go apiCfg.runWebhooks(ctx, webhookInterval)
+    go func() {
+        err := events.Listen(ctx, dbURL, eventChannel, apiCfg.events)
+        if ctx.Err() == nil {
+            log.Fatalf("error listening for events: %s", err)
+        }
+    }()
 
     // Start server
     fmt.Printf("Serving files from / on port: %v\n", port)
-    log.Fatal(server.ListenAndServe())
+    server.RegisterOnShutdown(apiCfg.live.drain)
+    go func() {
+        err := server.ListenAndServe()
+        if !errors.Is(err, http.ErrServerClosed) {
+            log.Fatal(err)
+        }
+    }()
+
+    // on a signal, stop taking requests, let the ones in flight finish and
+    // close streams and sockets so their clients reconnect elsewhere
+    <-ctx.Done()
+    fmt.Println("Shutting down")
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        log.Printf("error shutting down server: %s", err)
+    }
+    if err := apiCfg.live.wait(shutdownCtx); err != nil {
+        log.Printf("error draining connections: %s", err)
+    }
 }
 
+// read an optional duration from the environment
+func envDuration(name string, fallback time.Duration) time.Duration {
+    value := os.Getenv(name)
+    if value == "" {
+        return fallback
+    }
+    duration, err := time.ParseDuration(value)
+    if err != nil {
+        log.Fatalf("%s must be a duration: %s", name, err)
+    }
+    return duration
+}
+
+func envInt(name string, fallback int) int {
+    value := os.Getenv(name)
+    if value == "" {
+        return fallback
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < 1 {
+        log.Fatalf("%s must be a positive integer", name)
+    }
+    return n
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/media"
+	"github.com/google/uuid"
+)
+
+// most media a chirp can carry
+const maxChirpMedia = 4
+
+// media attached to a chirp, served through the API whatever the store
+type ChirpMedia struct {
+    ID            uuid.UUID  `json:"id"`
+    ContentType   string     `json:"content_type"`
+    Width         int32      `json:"width"`
+    Height        int32      `json:"height"`
+    URL           string     `json:"url"`
+    ThumbnailURL  string     `json:"thumbnail_url"`
+}
+
+// build the blob store from the environment, keeping files on local disk
+// unless an S3 bucket is configured
+func newBlobStore() (media.BlobStore, error) {
+    switch store := os.Getenv("MEDIA_STORE"); store {
+    case "", "local":
+        dir := os.Getenv("MEDIA_DIR")
+        if dir == "" {
+            dir = "media"
+        }
+        return media.NewLocalStore(dir)
+    case "s3":
+        return media.NewS3Store(media.S3Config{
+            Endpoint:  os.Getenv("S3_ENDPOINT"),
+            Region:    os.Getenv("S3_REGION"),
+            Bucket:    os.Getenv("S3_BUCKET"),
+            AccessKey: os.Getenv("S3_ACCESS_KEY"),
+            SecretKey: os.Getenv("S3_SECRET_KEY"),
+        })
+    default:
+        return nil, fmt.Errorf("unknown media store '%s'", store)
+    }
+}
+
+func chirpMediaFromDB(attachment database.MediaAttachment) ChirpMedia {
+    url := fmt.Sprintf("/api/media/%s", attachment.ID)
+    return ChirpMedia{
+        ID:           attachment.ID,
+        ContentType:  attachment.ContentType,
+        Width:        attachment.Width,
+        Height:       attachment.Height,
+        URL:          url,
+        ThumbnailURL: url + "/thumbnail",
+    }
+}
+
+// load the media of a batch of chirps onto the recast chirps
+func (cfg *apiConfig) attachChirpMedia(ctx context.Context, items []Chirp, ids []uuid.UUID) error {
+    byID := make(map[uuid.UUID]*[]ChirpMedia, len(items))
+    for i := range items {
+        items[i].Media = []ChirpMedia{}
+        byID[items[i].ID] = &items[i].Media
+    }
+
+    attachments, err := cfg.dbQueries.GetMediaForChirps(ctx, ids)
+    if err != nil {
+        return err
+    }
+    for _, attachment := range attachments {
+        m := byID[attachment.ChirpID.UUID]
+        *m = append(*m, chirpMediaFromDB(attachment))
+    }
+    return nil
+}
+
+// remove the blobs of deleted media once the delete is committed, logging
+// failures since the rows are already gone
+func (cfg *apiConfig) removeMediaBlobs(ctx context.Context, attachments []database.MediaAttachment) {
+    for _, attachment := range attachments {
+        for _, key := range []string{attachment.BlobKey, attachment.ThumbnailKey} {
+            if err := cfg.blobs.Delete(ctx, key); err != nil {
+                log.Printf("error removing media blob %s: %s", key, err)
+            }
+        }
+    }
+}
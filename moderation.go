@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// moderation state of a chirp, only visible chirps are listed
+const (
+    chirpStateVisible  = moderation.StateVisible
+    chirpStateHeld     = moderation.StateHeld
+    chirpStateHidden   = moderation.StateHidden
+)
+
+// announce a moderator's change of a chirp's state within their transaction.
+// Live clients drop a chirp that stops being visible, announced as it was so
+// that whoever saw it hears of it, and get one that becomes visible as new.
+func announceModeration(ctx context.Context, qtx *database.Queries, before, after database.Chirp) error {
+    if before.DeletedAt.Valid || before.ModerationState == after.ModerationState {
+        return nil
+    }
+    if before.ModerationState == chirpStateVisible {
+        return publishEvent(ctx, qtx, eventChirpDeleted, chirpEventData(before))
+    }
+    if after.ModerationState == chirpStateVisible {
+        return publishEvent(ctx, qtx, eventChirpCreated, chirpEventData(after))
+    }
+    return nil
+}
+
+// words masked when no moderation config is given
+var defaultBadWords = []string{"kerfuffle", "sharbert", "fornax"}
+
+// moderation details shown to the author of a chirp
+type ChirpModeration struct {
+    State  string    `json:"state"`
+    Rules  []string  `json:"rules"`
+}
+
+func newModerator(ctx context.Context, configPath string) (*moderation.Moderator, error) {
+    // fall back on masking the default words
+    if configPath == "" {
+        wl := moderation.NewWordList("bad-words", moderation.ActionMask, defaultBadWords)
+        return moderation.NewModerator(moderation.NewPipeline(wl)), nil
+    }
+
+    // load the configured pipeline and reload it whenever its files change
+    pipeline, err := moderation.LoadPipeline(configPath)
+    if err != nil {
+        return nil, err
+    }
+    moderator := moderation.NewModerator(pipeline)
+    go moderator.WatchConfig(ctx, configPath, 10*time.Second)
+    log.Printf("loaded moderation config from %s", configPath)
+    return moderator, nil
+}
+
+// longest chirp the user can post, which is longer for Chirpy Red users
+func (cfg *apiConfig) chirpLengthLimit(ctx context.Context, userID uuid.UUID) (int, error) {
+    user, err := cfg.dbQueries.GetUser(ctx, userID)
+    if err != nil {
+        return 0, err
+    }
+    if user.IsChirpyRed {
+        return cfg.maxChirpLengthRed, nil
+    }
+    return cfg.maxChirpLength, nil
+}
+
+// normalize the chirp, check its length in characters and run it through the
+// moderation pipeline, on both creation and edit
+func (cfg *apiConfig) validateChirpBody(body string, maxLength int) (moderation.Result, error) {
+    body = chirptext.Normalize(body)
+    if length := chirptext.Length(body); length > maxLength {
+        return moderation.Result{}, fmt.Errorf("Chirp is too long: %d characters, at most %d allowed", length, maxLength)
+    }
+    result := cfg.moderator.Moderate(body)
+    if result.Action == moderation.ActionReject {
+        return result, fmt.Errorf("Chirp rejected by moderation rules: %s", strings.Join(result.Rules, ", "))
+    }
+    return result, nil
+}
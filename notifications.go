@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// types of notification, each of which users can turn off
+const (
+    notifyReply       = "reply"
+    notifyMention     = "mention"
+    notifyQuote       = "quote"
+    notifyLike        = "like"
+    notifyRechirp     = "rechirp"
+    notifyFollow      = "follow"
+    notifyRedUpgrade  = "red_upgrade"
+)
+
+var notificationTypes = []string{notifyReply, notifyMention, notifyQuote, notifyLike, notifyRechirp, notifyFollow, notifyRedUpgrade}
+
+// notify a user of an action, grouped with their unread notifications of the
+// same type about the same chirp when the type groups
+func notify(ctx context.Context, q *database.Queries, userID uuid.UUID, kind string, chirpID uuid.NullUUID, actorIDs ...uuid.UUID) error {
+    groupKey := sql.NullString{}
+    switch kind {
+    case notifyLike, notifyRechirp:
+        groupKey = sql.NullString{String: chirpID.UUID.String(), Valid: true}
+    case notifyFollow, notifyRedUpgrade:
+        groupKey = sql.NullString{String: kind, Valid: true}
+    }
+    if actorIDs == nil {
+        actorIDs = []uuid.UUID{}
+    }
+    n, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+        UserID:   userID,
+        Type:     kind,
+        ChirpID:  chirpID,
+        GroupKey: groupKey,
+        ActorIds: actorIDs,
+    })
+    if err != nil || n == 0 {
+        return err
+    }
+    return publishEvent(ctx, q, eventNotification, eventData{ChirpID: chirpID.UUID, UserID: userID, NotificationType: kind})
+}
+
+// notify the author of the chirp a new chirp replies to, the users it
+// mentions and the author of the chirp it quotes, if they can see it. A user
+// gets one notification, for the first of those that applies. Failures are
+// logged, since the chirp is already published.
+func (cfg *apiConfig) notifyNewChirp(ctx context.Context, chirp database.Chirp) {
+    if chirp.ModerationState != chirpStateVisible {
+        return
+    }
+    chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+    recipients := map[uuid.UUID]string{}
+    if chirp.ReplyTo.Valid {
+        parent, err := cfg.dbQueries.GetChirp(ctx, chirp.ReplyTo.UUID)
+        if err == nil {
+            recipients[parent.UserID] = notifyReply
+        }
+    }
+    mentions, err := cfg.dbQueries.GetMentionsForChirps(ctx, []uuid.UUID{chirp.ID})
+    if err != nil {
+        log.Printf("error getting mentions of chirp %s: %s", chirp.ID, err)
+        return
+    }
+    for _, mention := range mentions {
+        if _, ok := recipients[mention.UserID]; !ok {
+            recipients[mention.UserID] = notifyMention
+        }
+    }
+    if chirp.QuoteOf.Valid {
+        quoted, err := cfg.dbQueries.GetChirp(ctx, chirp.QuoteOf.UUID)
+        if err == nil {
+            if _, ok := recipients[quoted.UserID]; !ok {
+                recipients[quoted.UserID] = notifyQuote
+            }
+        }
+    }
+    for userID, kind := range recipients {
+        ok, err := cfg.canViewChirp(ctx, uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+        if err == nil && ok {
+            err = notify(ctx, cfg.dbQueries, userID, kind, chirpID, chirp.UserID)
+        }
+        if err != nil {
+            log.Printf("error notifying %s of chirp %s: %s", userID, chirp.ID, err)
+        }
+    }
+}
+
+// one line summary of a notification, like "alice and 4 others liked your chirp"
+func notificationSummary(kind string, actors []AuthorSummary, actorCount int32) string {
+    who := "Someone"
+    if len(actors) > 0 {
+        who = "@" + actors[0].Handle
+    }
+    switch {
+    case actorCount == 2:
+        who += " and 1 other"
+    case actorCount > 2:
+        who += fmt.Sprintf(" and %d others", actorCount-1)
+    }
+    switch kind {
+    case notifyReply:
+        return who + " replied to your chirp"
+    case notifyMention:
+        return who + " mentioned you"
+    case notifyQuote:
+        return who + " quoted your chirp"
+    case notifyLike:
+        return who + " liked your chirp"
+    case notifyRechirp:
+        return who + " rechirped your chirp"
+    case notifyFollow:
+        return who + " followed you"
+    case notifyRedUpgrade:
+        return "Welcome to Chirpy Red"
+    }
+    return kind
+}
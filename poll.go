@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// limits on the polls attached to chirps
+const (
+    minPollOptions       = 2
+    maxPollOptions       = 4
+    maxPollOptionLength  = 50
+    minPollDuration      = 5 * time.Minute
+    maxPollDuration      = 7 * 24 * time.Hour
+)
+
+// poll to attach to a new chirp
+type InitPoll struct {
+    Options   []string   `json:"options"`
+    ClosesAt  time.Time  `json:"closes_at"`
+}
+
+// poll as shown on a chirp. Tallies are left out until the viewer has voted
+// or the poll has closed.
+type ChirpPoll struct {
+    ClosesAt    time.Time     `json:"closes_at"`
+    Closed      bool          `json:"closed"`
+    Options     []PollOption  `json:"options"`
+    TotalVotes  *int32        `json:"total_votes,omitempty"`
+    MyVote      *int32        `json:"my_vote,omitempty"`
+}
+
+type PollOption struct {
+    Position  int32   `json:"position"`
+    Body      string  `json:"body"`
+    Votes     *int32  `json:"votes,omitempty"`
+}
+
+// check a poll request, trimming its options in place
+func validatePoll(poll *InitPoll) error {
+    if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+        return &chirpError{http.StatusBadRequest, fmt.Sprintf("a poll needs %d to %d options", minPollOptions, maxPollOptions), nil}
+    }
+    for i, option := range poll.Options {
+        option = chirptext.Normalize(strings.TrimSpace(option))
+        if option == "" || chirptext.Length(option) > maxPollOptionLength {
+            return &chirpError{http.StatusBadRequest, fmt.Sprintf("poll options must be 1 to %d characters", maxPollOptionLength), nil}
+        }
+        if slices.Contains(poll.Options[:i], option) {
+            return &chirpError{http.StatusBadRequest, "poll options must be distinct", nil}
+        }
+        poll.Options[i] = option
+    }
+    duration := time.Until(poll.ClosesAt)
+    if duration < minPollDuration || duration > maxPollDuration {
+        return &chirpError{http.StatusBadRequest, fmt.Sprintf("a poll must close between %s and %s from now", minPollDuration, maxPollDuration), nil}
+    }
+    return nil
+}
+
+// create the poll of a new chirp within its transaction
+func createPoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, poll *InitPoll) error {
+    _, err := qtx.CreatePoll(ctx, database.CreatePollParams{
+        ChirpID:  chirpID,
+        ClosesAt: poll.ClosesAt.UTC(),
+    })
+    if err != nil {
+        return err
+    }
+    return qtx.CreatePollOptions(ctx, database.CreatePollOptionsParams{
+        ChirpID: chirpID,
+        Bodies:  poll.Options,
+    })
+}
+
+// load the polls of a batch of chirps onto the recast chirps, with the
+// viewer's votes
+func (cfg *apiConfig) attachChirpPolls(ctx context.Context, viewer uuid.NullUUID, items []Chirp, ids []uuid.UUID) error {
+    polls, err := cfg.dbQueries.GetPollsForChirps(ctx, ids)
+    if err != nil || len(polls) == 0 {
+        return err
+    }
+    byID := make(map[uuid.UUID]*ChirpPoll, len(polls))
+    for _, poll := range polls {
+        byID[poll.ChirpID] = &ChirpPoll{
+            ClosesAt: poll.ClosesAt,
+            Closed:   !time.Now().Before(poll.ClosesAt),
+            Options:  []PollOption{},
+        }
+    }
+    if viewer.Valid {
+        votes, err := cfg.dbQueries.GetPollVotesByUser(ctx, database.GetPollVotesByUserParams{
+            UserID:   viewer.UUID,
+            ChirpIds: ids,
+        })
+        if err != nil {
+            return err
+        }
+        for _, vote := range votes {
+            byID[vote.ChirpID].MyVote = &vote.Position
+        }
+    }
+
+    // tallies are only shown once the viewer can no longer be swayed by them
+    options, err := cfg.dbQueries.GetPollOptionsForChirps(ctx, ids)
+    if err != nil {
+        return err
+    }
+    for _, option := range options {
+        poll := byID[option.ChirpID]
+        item := PollOption{Position: option.Position, Body: option.Body}
+        if poll.Closed || poll.MyVote != nil {
+            votes := option.Votes
+            item.Votes = &votes
+            if poll.TotalVotes == nil {
+                poll.TotalVotes = new(int32)
+            }
+            *poll.TotalVotes += votes
+        }
+        poll.Options = append(poll.Options, item)
+    }
+
+    for i := range items {
+        items[i].Poll = byID[items[i].ID]
+    }
+    return nil
+}
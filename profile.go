@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/CraigYanitski/server-test/internal/chirptext"
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// limits on the public profile fields
+const (
+    maxDisplayNameLength  = 50
+    maxBioLength          = 160
+)
+
+// handles are 3 to 15 letters, digits or underscores, compared ignoring case
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+// compact author shown on each chirp
+type AuthorSummary struct {
+    ID           uuid.UUID  `json:"id"`
+    Handle       string     `json:"handle"`
+    DisplayName  string     `json:"display_name"`
+    AvatarURL    string     `json:"avatar_url,omitempty"`
+    IsChirpyRed  bool       `json:"is_chirpy_red"`
+}
+
+func avatarURL(user database.User) string {
+    if !user.AvatarMediaID.Valid {
+        return ""
+    }
+    return fmt.Sprintf("/api/media/%s/thumbnail", user.AvatarMediaID.UUID)
+}
+
+func authorFromDB(user database.User) AuthorSummary {
+    return AuthorSummary{
+        ID:          user.ID,
+        Handle:      user.Handle,
+        DisplayName: user.DisplayName,
+        AvatarURL:   avatarURL(user),
+        IsChirpyRed: user.IsChirpyRed,
+    }
+}
+
+func validateHandle(handle string) error {
+    if !handlePattern.MatchString(handle) {
+        return fmt.Errorf("handles must be 3 to 15 letters, digits or underscores")
+    }
+    return nil
+}
+
+// normalize a free text profile field and check its length in characters
+func normalizeProfileText(name, value string, maxLength int) (string, error) {
+    value = chirptext.Normalize(strings.TrimSpace(value))
+    if length := chirptext.Length(value); length > maxLength {
+        return "", fmt.Errorf("%s is too long: %d characters, at most %d allowed", name, length, maxLength)
+    }
+    return value, nil
+}
+
+// load the authors of a batch of chirps onto the recast chirps
+func (cfg *apiConfig) attachChirpAuthors(ctx context.Context, items []Chirp) error {
+    ids := []uuid.UUID{}
+    for _, item := range items {
+        ids = append(ids, item.UserID)
+    }
+    users, err := cfg.dbQueries.GetUsersByIDs(ctx, ids)
+    if err != nil {
+        return err
+    }
+    byID := make(map[uuid.UUID]AuthorSummary, len(users))
+    for _, user := range users {
+        byID[user.ID] = authorFromDB(user)
+    }
+    for i := range items {
+        if author, ok := byID[items[i].UserID]; ok {
+            items[i].Author = &author
+        }
+    }
+    return nil
+}
+
+// look a user up by ID or by handle, with or without the '@'
+func (cfg *apiConfig) lookupUser(ctx context.Context, key string) (database.User, error) {
+    if id, err := uuid.Parse(key); err == nil {
+        return cfg.dbQueries.GetUser(ctx, id)
+    }
+    return cfg.dbQueries.GetUserByHandle(ctx, strings.TrimPrefix(key, "@"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const purgeInterval = time.Hour
+
+func (cfg *apiConfig) runPurger(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cfg.purgeDeletedChirps(ctx)
+            cfg.purgeWebhookDeliveries(ctx)
+        }
+    }
+}
+
+// hard delete chirps that were deleted before the retention period, along
+// with their media. Rechirps of them go too, through the foreign key.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) {
+    retention := int32(cfg.retention.Seconds())
+    tx, err := cfg.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Printf("error starting purge: %s", err)
+        return
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    media, err := qtx.PurgeDeletedChirpMedia(ctx, retention)
+    if err != nil {
+        log.Printf("error purging media of deleted chirps: %s", err)
+        return
+    }
+    purged, err := qtx.PurgeDeletedChirps(ctx, retention)
+    if err != nil {
+        log.Printf("error purging deleted chirps: %s", err)
+        return
+    }
+    if err = tx.Commit(); err != nil {
+        log.Printf("error committing purge: %s", err)
+        return
+    }
+
+    cfg.removeMediaBlobs(ctx, media)
+    if purged > 0 {
+        log.Printf("purged %d deleted chirps", purged)
+    }
+}
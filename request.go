@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// page sizes for cursor paginated lists
+const (
+    defaultPageSize  = 20
+    maxPageSize      = 100
+)
+
+// position in a list ordered newest first by time and then ID
+type pageCursor struct {
+    createdAt  time.Time
+    id         uuid.UUID
+}
+
+// the first page reads from after any stored row
+var firstPage = pageCursor{
+    createdAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+    id:        uuid.Max,
+}
+
+func parsePathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+    // read the path value and respond with an error if it is not a UUID
+    value := r.PathValue(name)
+    if value == "" {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("no %s given", name), nil)
+        return uuid.Nil, false
+    }
+    id, err := uuid.Parse(value)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, fmt.Sprintf("error parsing UUID from %s", name), err)
+        return uuid.Nil, false
+    }
+    return id, true
+}
+
+// opaque cursor for the page after the given row
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+    raw := fmt.Sprintf("%s,%s", createdAt.UTC().Format(time.RFC3339Nano), id)
+    return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return pageCursor{}, err
+    }
+    at, id, ok := strings.Cut(string(raw), ",")
+    if !ok {
+        return pageCursor{}, fmt.Errorf("malformed cursor")
+    }
+    createdAt, err := time.Parse(time.RFC3339Nano, at)
+    if err != nil {
+        return pageCursor{}, err
+    }
+    parsed, err := uuid.Parse(id)
+    if err != nil {
+        return pageCursor{}, err
+    }
+    return pageCursor{createdAt: createdAt, id: parsed}, nil
+}
+
+// read the cursor and limit query parameters, responding with an error if
+// either is invalid
+func parsePage(w http.ResponseWriter, r *http.Request) (pageCursor, int32, bool) {
+    cursor := firstPage
+    if value := r.URL.Query().Get("cursor"); value != "" {
+        var err error
+        cursor, err = decodeCursor(value)
+        if err != nil {
+            respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+            return pageCursor{}, 0, false
+        }
+    }
+    limit := defaultPageSize
+    if value := r.URL.Query().Get("limit"); value != "" {
+        var err error
+        limit, err = strconv.Atoi(value)
+        if err != nil || limit < 1 || limit > maxPageSize {
+            respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize), err)
+            return pageCursor{}, 0, false
+        }
+    }
+    return cursor, int32(limit), true
+}
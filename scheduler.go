@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+)
+
+const (
+    schedulerInterval  = 15 * time.Second
+    schedulerBatch     = 50
+)
+
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+    // publish anything that fell due while the server was down, then on
+    // every tick
+    cfg.publishDueDrafts(ctx)
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cfg.publishDueDrafts(ctx)
+        }
+    }
+}
+
+func (cfg *apiConfig) publishDueDrafts(ctx context.Context) {
+    for {
+        published, err := cfg.publishDueBatch(ctx)
+        if err != nil {
+            log.Printf("error publishing scheduled chirps: %s", err)
+            return
+        }
+        if published < schedulerBatch {
+            return
+        }
+    }
+}
+
+// publish a batch of due drafts in one transaction. The drafts are locked
+// with SKIP LOCKED, so several instances can run this at once and each draft
+// is published by exactly one of them, and deleted in the same transaction.
+func (cfg *apiConfig) publishDueBatch(ctx context.Context) (int, error) {
+    tx, err := cfg.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+    qtx := cfg.dbQueries.WithTx(tx)
+    drafts, err := qtx.GetDueDrafts(ctx, schedulerBatch)
+    if err != nil {
+        return 0, err
+    }
+
+    published := []database.Chirp{}
+    for _, draft := range drafts {
+        // a savepoint lets one draft fail without losing the rest
+        if _, err = tx.ExecContext(ctx, "SAVEPOINT publish_draft"); err != nil {
+            return 0, err
+        }
+        chirp, err := cfg.publishDraft(ctx, qtx, draft)
+        if err == nil {
+            published = append(published, chirp)
+            continue
+        }
+
+        // otherwise unschedule the draft, or it would block the drafts behind
+        // it on every tick, and keep the reason for its author. Problems that
+        // are not the author's are logged and reported without detail.
+        failure := err
+        if _, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT publish_draft"); err != nil {
+            return 0, err
+        }
+        reason := "error publishing chirp"
+        var cerr *chirpError
+        if errors.As(failure, &cerr) {
+            reason = cerr.Error()
+        } else {
+            log.Printf("error publishing draft %s: %s", draft.ID, failure)
+        }
+        err = qtx.FailDraft(ctx, database.FailDraftParams{
+            ID:        draft.ID,
+            LastError: sql.NullString{String: reason, Valid: true},
+        })
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    if err = tx.Commit(); err != nil {
+        return 0, err
+    }
+    for _, chirp := range published {
+        cfg.notifyNewChirp(ctx, chirp)
+    }
+    return len(drafts), nil
+}
+
+// publish a draft as its author, checking it again since the author, the
+// quoted chirp or the moderation rules may have changed since it was saved
+func (cfg *apiConfig) publishDraft(ctx context.Context, qtx *database.Queries, draft database.Draft) (database.Chirp, error) {
+    _, err := qtx.GetActiveSuspension(ctx, draft.UserID)
+    if err == nil {
+        return database.Chirp{}, &chirpError{status: http.StatusForbidden, msg: "account suspended"}
+    } else if !errors.Is(err, sql.ErrNoRows) {
+        return database.Chirp{}, err
+    }
+    params, mediaIDs, err := cfg.prepareChirp(ctx, draft.UserID, &InitChirp{
+        Body:     draft.Body,
+        QuoteOf:  draft.QuoteOf,
+        ReplyTo:  draft.ReplyTo,
+        MediaIDs:   draft.MediaIds,
+        Visibility: draft.Visibility,
+    })
+    if err != nil {
+        return database.Chirp{}, err
+    }
+    chirp, err := cfg.createChirp(ctx, qtx, params, mediaIDs, nil)
+    if err != nil {
+        return database.Chirp{}, err
+    }
+    return chirp, qtx.DeletePublishedDraft(ctx, draft.ID)
+}
@@ -0,0 +1,54 @@
+-- name: BlockUser :execrows
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING ;
+
+-- name: UnblockUser :execrows
+DELETE FROM user_blocks
+WHERE blocker_id = $1
+AND blocked_id = $2 ;
+
+-- name: GetBlocksByUser :many
+SELECT * FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC ;
+
+-- name: MuteUser :execrows
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING ;
+
+-- name: UnmuteUser :execrows
+DELETE FROM user_mutes
+WHERE muter_id = $1
+AND muted_id = $2 ;
+
+-- name: GetMutesByUser :many
+SELECT * FROM user_mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC ;
+
+-- name: IsBlockedBetween :one
+-- whether either user has blocked the other
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_a) AND blocked_id = sqlc.arg(user_b))
+    OR (blocker_id = sqlc.arg(user_b) AND blocked_id = sqlc.arg(user_a))
+) ;
+
+-- name: GetHiddenUserIDs :many
+-- users whose chirps are left out of the viewer's lists: anyone they blocked
+-- or muted, and anyone who blocked them
+SELECT blocked_id AS user_id FROM user_blocks WHERE blocker_id = $1
+UNION
+SELECT blocker_id AS user_id FROM user_blocks WHERE blocked_id = $1
+UNION
+SELECT muted_id AS user_id FROM user_mutes WHERE muter_id = $1 ;
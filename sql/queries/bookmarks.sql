@@ -0,0 +1,67 @@
+-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING * ;
+
+-- name: GetBookmarkCollectionsByUser :many
+SELECT * FROM bookmark_collections
+WHERE user_id = $1
+ORDER BY name ;
+
+-- name: GetBookmarkCollection :one
+SELECT * FROM bookmark_collections
+WHERE id = $1
+AND user_id = $2 ;
+
+-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections
+SET name = $1, updated_at = NOW()
+WHERE id = $2
+AND user_id = $3
+RETURNING * ;
+
+-- name: DeleteBookmarkCollection :execrows
+DELETE FROM bookmark_collections
+WHERE id = $1
+AND user_id = $2 ;
+
+-- name: BookmarkChirp :one
+-- bookmarking a chirp again moves it to the given collection
+INSERT INTO bookmarks (user_id, chirp_id, collection_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO UPDATE
+SET collection_id = EXCLUDED.collection_id
+RETURNING * ;
+
+-- name: UnbookmarkChirp :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2 ;
+
+-- name: GetBookmarkedChirpIDs :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[]) ;
+
+-- name: GetBookmarks :many
+-- a page of the user's bookmarks, newest first, after the given cursor and
+-- optionally in one collection
+SELECT bookmarks.* FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg(collection_id)::UUID IS NULL OR bookmarks.collection_id = sqlc.narg(collection_id))
+AND (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.arg(before_created_at)::TIMESTAMP, sqlc.arg(before_chirp_id)::UUID)
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT sqlc.arg(page_size) ;
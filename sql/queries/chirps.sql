@@ -1,11 +1,17 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of, moderation_state, moderation_rules, visibility, reply_to, thread_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
 RETURNING * ;
 
@@ -14,17 +20,143 @@ DELETE from chirps ;
 
 -- name: GetChirps :many
 SELECT * FROM chirps 
+WHERE moderation_state = 'visible'
+AND visibility IN ('public', 'followers')
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
 ORDER BY created_at ;
 
 -- name: GetChirpsByUser :many
 SELECT * FROM chirps 
-WHERE user_id = $1 ;
+WHERE user_id = $1
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+) ;
 
 -- name: GetChirp :one
 SELECT * FROM chirps
+WHERE id = $1
+AND deleted_at IS NULL ;
+
+-- name: GetViewableChirpIDs :many
+-- which of the given followers and direct chirps the viewer can see, as the
+-- author, a follower of the author or a mentioned user
+SELECT id FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::UUID[])
+AND (
+    user_id = sqlc.arg(viewer_id)
+    OR (visibility = 'followers' AND user_id IN (
+        SELECT followee_id FROM follows
+        WHERE follower_id = sqlc.arg(viewer_id)
+    ))
+    OR (visibility = 'direct' AND id IN (
+        SELECT chirp_id FROM chirp_mentions
+        WHERE user_id = sqlc.arg(viewer_id)
+    ))
+) ;
+
+-- name: GetChirpIncludingDeleted :one
+SELECT * FROM chirps
 WHERE id = $1 ;
 
--- name: DeleteChirp :one
+-- name: SoftDeleteChirp :one
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING * ;
+
+-- name: RestoreChirp :one
+-- only the author can restore, only inside the restore window, and never a
+-- chirp removed by a moderator
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = sqlc.arg(id)
+AND user_id = sqlc.arg(user_id)
+AND moderation_state <> 'hidden'
+AND deleted_at >= NOW() - sqlc.arg(window_seconds)::INTEGER * INTERVAL '1 second'
+RETURNING * ;
+
+-- name: GetDeletedChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC ;
+
+-- name: PurgeDeletedChirpMedia :many
+-- remove the media of chirps deleted before the retention period, returning
+-- them so their blobs can be removed too
+DELETE FROM media_attachments
+WHERE chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < NOW() - sqlc.arg(retention_seconds)::INTEGER * INTERVAL '1 second'
+)
+RETURNING * ;
+
+-- name: PurgeDeletedChirps :execrows
 DELETE FROM chirps
+WHERE deleted_at < NOW() - sqlc.arg(retention_seconds)::INTEGER * INTERVAL '1 second' ;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::UUID[])
+AND moderation_state = 'visible'
+AND deleted_at IS NULL ;
+
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1
+AND deleted_at IS NULL
+FOR UPDATE ;
+
+-- name: UpdateChirpBody :one
+-- only chirps still inside the edit window are updated
+UPDATE chirps
+SET body = sqlc.arg(body),
+    updated_at = NOW(),
+    edited_at = NOW(),
+    moderation_state = sqlc.arg(moderation_state),
+    moderation_rules = sqlc.arg(moderation_rules)
+WHERE id = sqlc.arg(id)
+AND deleted_at IS NULL
+AND created_at >= NOW() - sqlc.arg(window_seconds)::INTEGER * INTERVAL '1 second'
+RETURNING * ;
+
+-- name: SetChirpModerationState :one
+UPDATE chirps
+SET moderation_state = $2
 WHERE id = $1
 RETURNING * ;
+
+-- name: GetChirpsByModerationState :many
+SELECT * FROM chirps
+WHERE moderation_state = $1
+AND deleted_at IS NULL
+ORDER BY created_at ;
+
+-- name: GetHomeTimeline :many
+-- a page of the chirps and rechirps of the user and the accounts they follow,
+-- newest first, after the given cursor. Visibility is checked for the viewer
+-- afterwards.
+SELECT * FROM chirps
+WHERE (user_id = sqlc.arg(user_id) OR user_id IN (
+    SELECT followee_id FROM follows
+    WHERE follower_id = sqlc.arg(user_id)
+))
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
+AND (created_at, id) < (sqlc.arg(before_created_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size) ;
@@ -0,0 +1,85 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING * ;
+
+-- name: GetDirectConversation :one
+SELECT * FROM conversations
+WHERE direct_key = $1 ;
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1 ;
+
+-- name: AddConversationParticipants :exec
+-- adding someone who left brings them back
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+SELECT sqlc.arg(conversation_id)::UUID, unnest(sqlc.arg(user_ids)::UUID[]), NOW()
+ON CONFLICT (conversation_id, user_id) DO UPDATE
+SET left_at = NULL ;
+
+-- name: GetConversationParticipant :one
+SELECT * FROM conversation_participants
+WHERE conversation_id = $1
+AND user_id = $2 ;
+
+-- name: GetConversationParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::UUID[])
+ORDER BY joined_at, user_id ;
+
+-- name: GetConversationsForUser :many
+-- a page of the conversations the user has not left, most recently active
+-- first, after the given cursor
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = sqlc.arg(user_id)
+AND conversation_participants.left_at IS NULL
+AND (conversations.updated_at, conversations.id) < (sqlc.arg(before_updated_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(page_size) ;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1 ;
+
+-- name: LeaveConversation :execrows
+UPDATE conversation_participants
+SET left_at = NOW()
+WHERE conversation_id = $1
+AND user_id = $2
+AND left_at IS NULL ;
+
+-- name: SetConversationMuted :execrows
+UPDATE conversation_participants
+SET muted = $1
+WHERE conversation_id = $2
+AND user_id = $3
+AND left_at IS NULL ;
+
+-- name: IsBlockedAmong :one
+-- whether the user and any of the others have blocked one another
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = ANY(sqlc.arg(other_ids)::UUID[]))
+    OR (blocked_id = sqlc.arg(user_id) AND blocker_id = ANY(sqlc.arg(other_ids)::UUID[]))
+) ;
+
+-- name: IsBlockedWithin :one
+-- whether any two of the users have blocked one another
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE blocker_id = ANY(sqlc.arg(user_ids)::UUID[])
+    AND blocked_id = ANY(sqlc.arg(user_ids)::UUID[])
+) ;
+
+-- name: ResetConversations :exec
+DELETE FROM conversations ;
@@ -0,0 +1,40 @@
+-- name: ClaimDueDigests :many
+-- mark a batch of users as sent before sending, skipping rows another
+-- instance is claiming, so each digest goes out once
+UPDATE users
+SET digest_sent_at = NOW()
+WHERE id IN (
+    SELECT id FROM users
+    WHERE (digest_frequency = 'daily' AND digest_sent_at <= NOW() - INTERVAL '1 day')
+    OR (digest_frequency = 'weekly' AND digest_sent_at <= NOW() - INTERVAL '7 days')
+    ORDER BY digest_sent_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING * ;
+
+-- name: SetDigestFrequency :one
+-- opting in starts the first period now, rather than whenever the account
+-- was made
+UPDATE users
+SET digest_frequency = sqlc.arg(digest_frequency),
+    digest_sent_at = CASE
+        WHEN digest_frequency = 'off' AND sqlc.arg(digest_frequency) <> 'off' THEN NOW()
+        ELSE digest_sent_at
+    END
+WHERE id = sqlc.arg(id)
+RETURNING * ;
+
+-- name: GetTopFollowedChirps :many
+-- the most liked, rechirped and quoted chirps since a time from the
+-- accounts the user follows
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+AND follows.follower_id = sqlc.arg(user_id)
+WHERE chirps.created_at >= sqlc.arg(since)
+AND chirps.deleted_at IS NULL
+AND chirps.moderation_state = 'visible'
+AND chirps.rechirp_of IS NULL
+AND chirps.visibility IN ('public', 'unlisted', 'followers')
+ORDER BY chirps.like_count + chirps.rechirp_count + chirps.quote_count DESC, chirps.created_at DESC
+LIMIT sqlc.arg(page_size) ;
@@ -0,0 +1,63 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, quote_of, media_ids, publish_at, visibility, reply_to)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING * ;
+
+-- name: GetDraftsByUser :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY publish_at NULLS LAST, created_at ;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1
+AND user_id = $2 ;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET updated_at = NOW(),
+    body = $3,
+    quote_of = $4,
+    media_ids = $5,
+    publish_at = $6,
+    visibility = $7,
+    reply_to = $8,
+    last_error = NULL
+WHERE id = $1
+AND user_id = $2
+RETURNING * ;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE id = $1
+AND user_id = $2 ;
+
+-- name: GetDueDrafts :many
+-- lock due drafts for publishing, skipping any another instance holds
+SELECT * FROM drafts
+WHERE publish_at <= NOW()
+ORDER BY publish_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED ;
+
+-- name: DeletePublishedDraft :exec
+DELETE FROM drafts
+WHERE id = $1 ;
+
+-- name: FailDraft :exec
+UPDATE drafts
+SET updated_at = NOW(),
+    publish_at = NULL,
+    last_error = $2
+WHERE id = $1 ;
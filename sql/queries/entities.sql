@@ -0,0 +1,56 @@
+-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, start_index, end_index)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+) ;
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_index, end_index)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+) ;
+
+-- name: GetHashtagsForChirps :many
+SELECT * FROM chirp_hashtags
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+ORDER BY start_index ;
+
+-- name: GetMentionsForChirps :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+ORDER BY start_index ;
+
+-- name: GetChirpsByHashtag :many
+SELECT * FROM chirps
+WHERE id IN (
+    SELECT chirp_id FROM chirp_hashtags
+    WHERE tag = $1
+)
+AND moderation_state = 'visible'
+AND visibility IN ('public', 'followers')
+AND deleted_at IS NULL
+ORDER BY created_at DESC ;
+
+-- name: GetChirpsMentioningUser :many
+SELECT * FROM chirps
+WHERE id IN (
+    SELECT chirp_id FROM chirp_mentions
+    WHERE user_id = $1
+)
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+ORDER BY created_at DESC ;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1 ;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1 ;
@@ -0,0 +1,3 @@
+-- name: PublishEvent :exec
+-- events sent inside a transaction are only delivered once it commits
+SELECT pg_notify(sqlc.arg(channel)::TEXT, sqlc.arg(payload)::TEXT) ;
@@ -0,0 +1,38 @@
+-- name: CreateMediaAttachment :one
+INSERT INTO media_attachments (id, created_at, user_id, content_type, width, height, size_bytes, blob_key, thumbnail_key)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING * ;
+
+-- name: GetMediaAttachment :one
+-- media of a deleted chirp are not served
+SELECT * FROM media_attachments
+WHERE id = $1
+AND (chirp_id IS NULL OR chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at IS NULL
+)) ;
+
+-- name: AttachMediaToChirp :many
+-- attach the user's unattached uploads, in the order they were given
+UPDATE media_attachments
+SET chirp_id = sqlc.arg(chirp_id),
+    position = array_position(sqlc.arg(ids)::UUID[], id)
+WHERE id = ANY(sqlc.arg(ids)::UUID[])
+AND user_id = sqlc.arg(user_id)
+AND chirp_id IS NULL
+RETURNING * ;
+
+-- name: GetMediaForChirps :many
+SELECT * FROM media_attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+ORDER BY position ;
@@ -0,0 +1,55 @@
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING * ;
+
+-- name: GetMessage :one
+SELECT * FROM messages
+WHERE id = $1 ;
+
+-- name: GetMessages :many
+-- a page of a conversation's messages, newest first, after the given cursor
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size) ;
+
+-- name: GetLastMessages :many
+-- the latest message of each conversation
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::UUID[])
+ORDER BY conversation_id, created_at DESC, id DESC ;
+
+-- name: CountUnreadMessages :many
+-- messages from others after the user's read receipt, per conversation
+SELECT messages.conversation_id, COUNT(*) AS unread FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+AND conversation_participants.user_id = sqlc.arg(user_id)
+WHERE messages.conversation_id = ANY(sqlc.arg(conversation_ids)::UUID[])
+AND messages.sender_id IS DISTINCT FROM sqlc.arg(user_id)
+AND (conversation_participants.last_read_at IS NULL OR (messages.created_at, messages.id) > (conversation_participants.last_read_at, conversation_participants.last_read_message_id))
+GROUP BY messages.conversation_id ;
+
+-- name: MarkConversationRead :execrows
+-- move the user's read receipt up to the given message, or the latest one,
+-- never back
+UPDATE conversation_participants
+SET last_read_message_id = latest.id, last_read_at = latest.created_at
+FROM (
+    SELECT id, created_at FROM messages
+    WHERE conversation_id = sqlc.arg(conversation_id)
+    AND (sqlc.narg(message_id)::UUID IS NULL OR id = sqlc.narg(message_id))
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1
+) AS latest
+WHERE conversation_participants.conversation_id = sqlc.arg(conversation_id)
+AND conversation_participants.user_id = sqlc.arg(user_id)
+AND (conversation_participants.last_read_at IS NULL
+    OR (latest.created_at, latest.id) > (conversation_participants.last_read_at, conversation_participants.last_read_message_id)) ;
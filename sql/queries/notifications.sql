@@ -0,0 +1,100 @@
+-- name: CreateNotification :execrows
+-- notify a user, unless they turned the type off, the actor is themselves or
+-- someone they blocked, muted or were blocked by. An unread notification in
+-- the same group takes the new actor instead, keeping the latest ten.
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, chirp_id, group_key, actor_ids, actor_count)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    sqlc.arg(user_id)::UUID,
+    sqlc.arg(type)::TEXT,
+    sqlc.narg(chirp_id)::UUID,
+    sqlc.narg(group_key)::TEXT,
+    sqlc.arg(actor_ids)::UUID[],
+    cardinality(sqlc.arg(actor_ids)::UUID[])
+WHERE NOT sqlc.arg(user_id)::UUID = ANY(sqlc.arg(actor_ids)::UUID[])
+AND NOT EXISTS (
+    SELECT 1 FROM notification_preferences
+    WHERE notification_preferences.user_id = sqlc.arg(user_id)::UUID
+    AND notification_preferences.type = sqlc.arg(type)::TEXT
+    AND NOT enabled
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_id)::UUID AND blocked_id = ANY(sqlc.arg(actor_ids)::UUID[]))
+    OR (blocked_id = sqlc.arg(user_id)::UUID AND blocker_id = ANY(sqlc.arg(actor_ids)::UUID[]))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE muter_id = sqlc.arg(user_id)::UUID
+    AND muted_id = ANY(sqlc.arg(actor_ids)::UUID[])
+)
+ON CONFLICT (user_id, type, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW(),
+    actor_ids = (EXCLUDED.actor_ids || array_remove(notifications.actor_ids, EXCLUDED.actor_ids[1]))[1:10],
+    actor_count = notifications.actor_count + CASE
+        WHEN cardinality(EXCLUDED.actor_ids) = 0 OR EXCLUDED.actor_ids[1] = ANY(notifications.actor_ids) THEN 0
+        ELSE 1
+    END ;
+
+-- name: GetNotifications :many
+-- a page of the user's notifications, latest activity first, leaving out
+-- those about deleted chirps
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+AND (chirp_id IS NULL OR chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at IS NULL
+))
+AND (updated_at, id) < (sqlc.arg(before_updated_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg(page_size) ;
+
+-- name: GetUnreadNotifications :many
+-- the user's latest unread notifications, for their digest
+SELECT * FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL
+AND (chirp_id IS NULL OR chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at IS NULL
+))
+ORDER BY updated_at DESC, id DESC
+LIMIT $2 ;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL
+AND (chirp_id IS NULL OR chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at IS NULL
+)) ;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND read_at IS NULL ;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL ;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1 ;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, type) DO UPDATE
+SET enabled = EXCLUDED.enabled ;
@@ -0,0 +1,31 @@
+-- name: PinChirp :execrows
+-- pin within the user's limit. Concurrent pins could each see room for one
+-- more, so the user's row is locked first in the same transaction.
+INSERT INTO chirp_pins (chirp_id, user_id, created_at)
+SELECT sqlc.arg(chirp_id), sqlc.arg(user_id), NOW()
+WHERE (
+    SELECT COUNT(*) FROM chirp_pins
+    WHERE user_id = sqlc.arg(user_id)
+) < sqlc.arg(max_pins)::INTEGER
+ON CONFLICT DO NOTHING ;
+
+-- name: UnpinChirp :execrows
+DELETE FROM chirp_pins
+WHERE chirp_id = $1
+AND user_id = $2 ;
+
+-- name: DeleteChirpPin :exec
+DELETE FROM chirp_pins
+WHERE chirp_id = $1 ;
+
+-- name: GetPinnedChirpsByUser :many
+SELECT chirps.* FROM chirps
+JOIN chirp_pins ON chirp_pins.chirp_id = chirps.id
+WHERE chirp_pins.user_id = $1
+AND chirps.moderation_state = 'visible'
+AND chirps.deleted_at IS NULL
+ORDER BY chirp_pins.created_at DESC ;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM chirp_pins
+WHERE user_id = $1 ;
@@ -0,0 +1,47 @@
+-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, created_at, closes_at)
+VALUES (
+    $1,
+    NOW(),
+    $2
+)
+RETURNING * ;
+
+-- name: CreatePollOptions :exec
+-- number the options from 1 in the order they were given
+INSERT INTO poll_options (chirp_id, position, body)
+SELECT sqlc.arg(chirp_id), o.position::INTEGER, o.body
+FROM unnest(sqlc.arg(bodies)::TEXT[]) WITH ORDINALITY AS o(body, position) ;
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1 ;
+
+-- name: GetPollsForChirps :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[]) ;
+
+-- name: GetPollOptionsForChirps :many
+-- options with their vote tallies
+SELECT poll_options.chirp_id, poll_options.position, poll_options.body, COUNT(poll_votes.user_id)::INTEGER AS votes
+FROM poll_options
+LEFT JOIN poll_votes
+ON poll_votes.chirp_id = poll_options.chirp_id
+AND poll_votes.position = poll_options.position
+WHERE poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+GROUP BY poll_options.chirp_id, poll_options.position
+ORDER BY poll_options.chirp_id, poll_options.position ;
+
+-- name: GetPollVotesByUser :many
+SELECT * FROM poll_votes
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[]) ;
+
+-- name: VotePoll :execrows
+-- votes are only counted while the poll is open, and once per user
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+SELECT chirp_id, sqlc.arg(user_id), sqlc.arg(position), NOW()
+FROM polls
+WHERE chirp_id = sqlc.arg(chirp_id)
+AND closes_at > NOW()
+ON CONFLICT DO NOTHING ;
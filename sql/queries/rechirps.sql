@@ -0,0 +1,39 @@
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING
+RETURNING * ;
+
+-- name: DeleteRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1
+AND rechirp_of = $2
+AND deleted_at IS NULL
+RETURNING * ;
+
+-- name: IncrementChirpRechirps :exec
+UPDATE chirps
+SET rechirp_count = rechirp_count + 1
+WHERE id = $1 ;
+
+-- name: DecrementChirpRechirps :exec
+UPDATE chirps
+SET rechirp_count = rechirp_count - 1
+WHERE id = $1 ;
+
+-- name: IncrementChirpQuotes :exec
+UPDATE chirps
+SET quote_count = quote_count + 1
+WHERE id = $1 ;
+
+-- name: DecrementChirpQuotes :exec
+UPDATE chirps
+SET quote_count = quote_count - 1
+WHERE id = $1 ;
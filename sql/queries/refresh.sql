@@ -30,3 +30,22 @@ UPDATE refresh_tokens
 SET updated_at = NOW(),
     revoked_at = NOW()
 WHERE token = $1 ;
+
+-- name: RevokeUserRefreshTokens :exec
+-- sign the user out everywhere, when their password changes
+UPDATE refresh_tokens
+SET updated_at = NOW(),
+    revoked_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL ;
+
+-- name: RevokeUserAccessTokens :exec
+-- refuse the user's access tokens issued before now, when their credentials
+-- change
+UPDATE users
+SET tokens_valid_after = date_trunc('second', NOW())
+WHERE id = $1 ;
+
+-- name: GetUserTokensValidAfter :one
+SELECT tokens_valid_after FROM users
+WHERE id = $1 ;
@@ -0,0 +1,44 @@
+-- name: IncrementChirpReplies :exec
+UPDATE chirps
+SET reply_count = reply_count + 1
+WHERE id = $1 ;
+
+-- name: DecrementChirpReplies :exec
+UPDATE chirps
+SET reply_count = reply_count - 1
+WHERE id = $1 ;
+
+-- name: GetChirpReplies :many
+-- a page of the replies to a chirp, newest first, after the given cursor.
+-- Visibility is checked for the viewer afterwards.
+SELECT * FROM chirps
+WHERE reply_to = sqlc.arg(reply_to)
+AND moderation_state = 'visible'
+AND deleted_at IS NULL
+AND user_id NOT IN (
+    SELECT id FROM users
+    WHERE suspended_at IS NOT NULL
+    AND suspended_until IS NULL
+)
+AND (created_at, id) < (sqlc.arg(before_created_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size) ;
+
+-- name: GetChirpAncestors :many
+-- the chirps a reply answers, up to the first of its thread or max_depth
+-- chirps, oldest first. The walk stops at a purged chirp, while deleted and
+-- held ones are returned for the caller to leave out.
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.reply_to, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.reply_to = parent.id
+    WHERE child.id = sqlc.arg(id)
+    UNION ALL
+    SELECT parent.id, parent.reply_to, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON ancestors.reply_to = parent.id
+    WHERE ancestors.depth < sqlc.arg(max_depth)::INTEGER
+)
+SELECT * FROM chirps
+WHERE id IN (SELECT id FROM ancestors)
+ORDER BY created_at, id ;
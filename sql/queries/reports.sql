@@ -0,0 +1,70 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, chirp_id, chirp_author_id, chirp_body, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT DO NOTHING
+RETURNING * ;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1 ;
+
+-- name: GetReportsByReporter :many
+SELECT * FROM reports
+WHERE reporter_id = $1
+ORDER BY created_at DESC ;
+
+-- name: GetReportsByStatus :many
+SELECT * FROM reports
+WHERE status = $1
+ORDER BY created_at ;
+
+-- name: GetReportsForChirp :many
+SELECT * FROM reports
+WHERE chirp_id = $1
+ORDER BY created_at ;
+
+-- name: CountActionedReportsForAuthor :one
+SELECT COUNT(*) FROM reports
+WHERE chirp_author_id = $1
+AND status = 'actioned' ;
+
+-- name: ResolveChirpReports :many
+-- a moderator acts on the chirp, so every open report on it is resolved
+UPDATE reports
+SET updated_at = NOW(),
+    status = sqlc.arg(status),
+    resolution = sqlc.arg(resolution),
+    resolution_note = sqlc.arg(resolution_note),
+    resolved_by = sqlc.arg(resolved_by),
+    resolved_at = NOW()
+WHERE chirp_id = sqlc.arg(chirp_id)
+AND status = 'open'
+RETURNING * ;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, chirp_id, target_user_id, report_id, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+) ;
+
+-- name: GetModerationActionsForChirp :many
+SELECT * FROM moderation_actions
+WHERE chirp_id = $1
+ORDER BY created_at ;
@@ -0,0 +1,11 @@
+-- name: CreateChirpRevision :exec
+-- copy the current body of the chirp before it is replaced
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+SELECT gen_random_uuid(), id, body, updated_at
+FROM chirps
+WHERE id = $1 ;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at ;
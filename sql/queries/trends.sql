@@ -0,0 +1,37 @@
+-- name: AggregateHashtagBuckets :exec
+-- recount every bucket from the latest one onward, so a partly filled bucket
+-- is completed on the next run
+INSERT INTO hashtag_buckets (tag, bucket_start, uses)
+SELECT
+    chirp_hashtags.tag,
+    date_bin('5 minutes', chirps.created_at, TIMESTAMP '2000-01-01'),
+    COUNT(*)
+FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirps.moderation_state = 'visible'
+AND chirps.visibility = 'public'
+AND chirps.deleted_at IS NULL
+AND chirps.created_at >= COALESCE(
+    (SELECT MAX(bucket_start) FROM hashtag_buckets),
+    NOW() - INTERVAL '8 days'
+)
+GROUP BY 1, 2
+ON CONFLICT (tag, bucket_start) DO UPDATE
+SET uses = EXCLUDED.uses ;
+
+-- name: PruneHashtagBuckets :exec
+DELETE FROM hashtag_buckets
+WHERE bucket_start < NOW() - INTERVAL '8 days' ;
+
+-- name: GetHashtagWindowCounts :many
+SELECT
+    tag,
+    COALESCE(SUM(uses) FILTER (
+        WHERE bucket_start >= NOW() - sqlc.arg(recent_seconds)::INTEGER * INTERVAL '1 second'
+    ), 0)::BIGINT AS recent,
+    COALESCE(SUM(uses) FILTER (
+        WHERE bucket_start < NOW() - sqlc.arg(recent_seconds)::INTEGER * INTERVAL '1 second'
+    ), 0)::BIGINT AS baseline
+FROM hashtag_buckets
+WHERE bucket_start >= NOW() - sqlc.arg(total_seconds)::INTEGER * INTERVAL '1 second'
+GROUP BY tag ;
@@ -1,11 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+-- users who do not choose a handle get a generated one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
-    $1,
-    $2
+    sqlc.arg(email),
+    sqlc.arg(hashed_password),
+    COALESCE(NULLIF(sqlc.arg(handle)::TEXT, ''), 'user_' || substr(md5(random()::TEXT), 1, 10))
 )
 RETURNING * ;
 
@@ -29,3 +31,100 @@ UPDATE users
 SET is_chirpy_red = true
 WHERE id = $1 
 RETURNING * ;
+
+-- name: GetExistingUserIDs :many
+SELECT id FROM users
+WHERE id = ANY(sqlc.arg(ids)::UUID[]) ;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = $1 ;
+
+-- name: GetUserForUpdate :one
+-- lock the user's row, to serialize changes counted against their limits
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE ;
+
+-- name: SetUserRole :one
+UPDATE users
+SET updated_at = NOW(),
+    role = $2
+WHERE id = $1
+RETURNING * ;
+
+-- name: PromoteAdminByEmail :execrows
+-- make the account with this email an admin, to bootstrap the first one
+UPDATE users
+SET updated_at = NOW(),
+    role = 'admin'
+WHERE email = $1
+AND role <> 'admin' ;
+
+-- name: SuspendUser :one
+UPDATE users
+SET updated_at = NOW(),
+    suspended_at = NOW(),
+    suspended_until = NOW() + NULLIF(sqlc.arg(duration_seconds)::INTEGER, 0) * INTERVAL '1 second',
+    suspension_reason = sqlc.arg(suspension_reason)
+WHERE id = sqlc.arg(id)
+RETURNING * ;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET updated_at = NOW(),
+    suspended_at = NULL,
+    suspended_until = NULL,
+    suspension_reason = NULL
+WHERE id = $1
+RETURNING * ;
+
+-- name: GetActiveSuspension :one
+SELECT suspended_until, suspension_reason FROM users
+WHERE id = $1
+AND suspended_at IS NOT NULL
+AND (suspended_until IS NULL OR suspended_until > NOW()) ;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER($1) ;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::UUID[]) ;
+
+-- name: GetUserIDsByHandles :many
+-- users for a batch of lowercase handles
+SELECT id, LOWER(handle) AS handle FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg(handles)::TEXT[]) ;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = NOW(),
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    avatar_media_id = $5
+WHERE id = $1
+RETURNING * ;
+
+-- name: GetUserProfileCounts :one
+-- the chirp count is of what the user's chirp list shows an anonymous viewer,
+-- whoever is asking, so it reveals nothing about restricted chirps
+SELECT
+    (SELECT COUNT(*) FROM chirps
+     WHERE chirps.user_id = sqlc.arg(id)
+     AND chirps.moderation_state = 'visible'
+     AND chirps.deleted_at IS NULL
+     AND chirps.visibility IN ('public', 'unlisted')
+     AND (chirps.rechirp_of IS NULL OR EXISTS (
+         SELECT 1 FROM chirps originals
+         WHERE originals.id = chirps.rechirp_of
+         AND originals.moderation_state = 'visible'
+         AND originals.deleted_at IS NULL
+         AND originals.visibility IN ('public', 'unlisted')
+     ))) AS chirp_count,
+    (SELECT COUNT(*) FROM follows
+     WHERE follows.followee_id = sqlc.arg(id)) AS follower_count,
+    (SELECT COUNT(*) FROM follows
+     WHERE follows.follower_id = sqlc.arg(id)) AS following_count ;
@@ -0,0 +1,105 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, scope, url, event_types, secret)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING * ;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 ;
+
+-- name: GetWebhooksByUser :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at DESC ;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $1, event_types = $2, active = $3, updated_at = NOW()
+WHERE id = $4
+AND user_id = $5
+RETURNING * ;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1
+AND user_id = $2 ;
+
+-- name: EnqueueWebhookDeliveries :exec
+-- queue an event for the active webhooks that want it: those of the given
+-- users, and app webhooks if the event is public
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, event_id, event_type, event_data, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), id, sqlc.arg(event_id), sqlc.arg(event_type), sqlc.arg(event_data)::TEXT::JSONB, NOW()
+FROM webhooks
+WHERE active
+AND sqlc.arg(event_type)::TEXT = ANY(event_types)
+AND ((scope = 'user' AND user_id = ANY(sqlc.arg(user_ids)::UUID[]))
+    OR (scope = 'app' AND sqlc.arg(public)::BOOLEAN)) ;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- lease a batch of due deliveries for longer than an attempt can take,
+-- skipping rows another instance is claiming. A delivery whose instance dies
+-- mid-attempt is picked up again when its lease runs out.
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING * ;
+
+-- name: RecordWebhookAttempt :exec
+-- log an attempt and move the delivery on to its next state
+WITH attempt AS (
+    INSERT INTO webhook_attempts (id, delivery_id, attempted_at, status_code, error, duration_ms)
+    VALUES (gen_random_uuid(), sqlc.arg(id), sqlc.arg(attempted_at), sqlc.narg(status_code), sqlc.narg(error), sqlc.arg(duration_ms))
+)
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status), attempts = attempts + 1, next_attempt_at = sqlc.arg(next_attempt_at), updated_at = NOW()
+WHERE id = sqlc.arg(id) ;
+
+-- name: SkipWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'skipped', updated_at = NOW()
+WHERE id = $1 ;
+
+-- name: GetWebhookDeliveries :many
+-- a page of a webhook's deliveries, newest first, after the given cursor
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg(webhook_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size) ;
+
+-- name: GetWebhookAttempts :many
+SELECT * FROM webhook_attempts
+WHERE delivery_id = ANY(sqlc.arg(delivery_ids)::UUID[])
+ORDER BY attempted_at ;
+
+-- name: RetryWebhookDelivery :one
+-- give a dead delivery a fresh set of attempts
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND webhook_id = $2
+AND status = 'dead'
+RETURNING * ;
+
+-- name: PurgeWebhookDeliveries :execrows
+-- delete deliveries that finished before the retention period, with their
+-- attempts
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending'
+AND updated_at < NOW() - sqlc.arg(retention_seconds)::INTEGER * INTERVAL '1 second' ;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- a rechirp is a chirp row pointing at its original, so it is removed with it;
+-- quote_of has no foreign key so that quotes outlive the chirp they quote
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID REFERENCES chirps ON DELETE CASCADE,
+ADD COLUMN quote_of UUID,
+ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN quote_count INTEGER NOT NULL DEFAULT 0 ;
+
+CREATE UNIQUE INDEX chirps_rechirp_unique
+ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL ;
+
+-- +goose Down
+DROP INDEX chirps_rechirp_unique ;
+
+ALTER TABLE chirps
+DROP COLUMN rechirp_of,
+DROP COLUMN quote_of,
+DROP COLUMN rechirp_count,
+DROP COLUMN quote_count ;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags (
+    chirp_id UUID NOT NULL REFERENCES chirps ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    start_index INTEGER NOT NULL,
+    end_index INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, start_index)
+) ;
+
+CREATE INDEX chirp_hashtags_tag ON chirp_hashtags (tag) ;
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL REFERENCES chirps ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    start_index INTEGER NOT NULL,
+    end_index INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, start_index)
+) ;
+
+CREATE INDEX chirp_mentions_user ON chirp_mentions (user_id) ;
+
+-- +goose Down
+DROP TABLE chirp_mentions ;
+
+DROP TABLE chirp_hashtags ;
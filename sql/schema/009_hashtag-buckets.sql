@@ -0,0 +1,13 @@
+-- +goose Up
+-- hashtag uses per 5 minute bucket, filled in by the trends aggregator
+CREATE TABLE hashtag_buckets (
+    tag TEXT NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    uses INTEGER NOT NULL,
+    PRIMARY KEY (tag, bucket_start)
+) ;
+
+CREATE INDEX hashtag_buckets_start ON hashtag_buckets (bucket_start) ;
+
+-- +goose Down
+DROP TABLE hashtag_buckets ;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- every body a chirp had before an edit, with the time it was written
+CREATE TABLE chirp_revisions (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+) ;
+
+CREATE INDEX chirp_revisions_chirp ON chirp_revisions (chirp_id, created_at) ;
+
+ALTER TABLE chirps
+ADD COLUMN edited_at TIMESTAMP ;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN edited_at ;
+
+DROP TABLE chirp_revisions ;
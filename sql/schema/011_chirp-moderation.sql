@@ -0,0 +1,11 @@
+-- +goose Up
+-- held chirps wait for review and are not listed, and the names of the
+-- moderation rules a chirp triggered are kept with it
+ALTER TABLE chirps
+ADD COLUMN moderation_state TEXT NOT NULL DEFAULT 'visible',
+ADD COLUMN moderation_rules TEXT[] NOT NULL DEFAULT '{}' ;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN moderation_state,
+DROP COLUMN moderation_rules ;
@@ -0,0 +1,56 @@
+-- +goose Up
+-- moderators act on reports, and suspended users stay suspended until
+-- suspended_until, or for good when it is NULL
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user',
+ADD COLUMN suspended_at TIMESTAMP,
+ADD COLUMN suspended_until TIMESTAMP,
+ADD COLUMN suspension_reason TEXT ;
+
+-- the chirp is copied into the report so the evidence outlives a deletion
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    chirp_id UUID NOT NULL,
+    chirp_author_id UUID NOT NULL,
+    chirp_body TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolution TEXT,
+    resolution_note TEXT,
+    resolved_by UUID REFERENCES users ON DELETE SET NULL,
+    resolved_at TIMESTAMP
+) ;
+
+CREATE UNIQUE INDEX reports_open_unique
+ON reports (chirp_id, reporter_id)
+WHERE status = 'open' ;
+
+CREATE INDEX reports_status ON reports (status, created_at) ;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID REFERENCES users ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    chirp_id UUID,
+    target_user_id UUID,
+    report_id UUID,
+    note TEXT NOT NULL DEFAULT ''
+) ;
+
+CREATE INDEX moderation_actions_chirp ON moderation_actions (chirp_id) ;
+
+-- +goose Down
+DROP TABLE moderation_actions ;
+
+DROP TABLE reports ;
+
+ALTER TABLE users
+DROP COLUMN role,
+DROP COLUMN suspended_at,
+DROP COLUMN suspended_until,
+DROP COLUMN suspension_reason ;
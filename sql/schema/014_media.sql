@@ -0,0 +1,21 @@
+-- +goose Up
+-- uploads are attached to at most one chirp, in position order, and stay
+-- unattached until a chirp references them
+CREATE TABLE media_attachments (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirps ON DELETE CASCADE,
+    position INTEGER
+) ;
+
+CREATE INDEX media_attachments_chirp ON media_attachments (chirp_id, position) ;
+
+-- +goose Down
+DROP TABLE media_attachments ;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- drafts with a publish_at are scheduled chirps, and a draft that could not
+-- be published keeps the reason in last_error and is unscheduled
+CREATE TABLE drafts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    quote_of UUID,
+    media_ids UUID[] NOT NULL DEFAULT '{}',
+    publish_at TIMESTAMP,
+    last_error TEXT
+) ;
+
+CREATE INDEX drafts_user ON drafts (user_id) ;
+CREATE INDEX drafts_due ON drafts (publish_at) WHERE publish_at IS NOT NULL ;
+
+-- +goose Down
+DROP TABLE drafts ;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- deleted chirps are kept until they are purged after the retention period,
+-- and a deleted rechirp no longer stops the user rechirping again
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP ;
+
+CREATE INDEX chirps_deleted ON chirps (deleted_at) WHERE deleted_at IS NOT NULL ;
+
+DROP INDEX chirps_rechirp_unique ;
+
+CREATE UNIQUE INDEX chirps_rechirp_unique
+ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL
+AND deleted_at IS NULL ;
+
+-- +goose Down
+DROP INDEX chirps_rechirp_unique ;
+
+DELETE FROM chirps
+WHERE deleted_at IS NOT NULL ;
+
+CREATE UNIQUE INDEX chirps_rechirp_unique
+ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL ;
+
+DROP INDEX chirps_deleted ;
+
+ALTER TABLE chirps
+DROP COLUMN deleted_at ;
@@ -0,0 +1,51 @@
+-- +goose Up
+-- unlisted chirps stay out of the global and hashtag lists, followers chirps
+-- are seen by the author's followers, and direct chirps by the users they
+-- mention
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' ;
+
+ALTER TABLE drafts
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' ;
+
+CREATE TABLE follows (
+    follower_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id)
+) ;
+
+CREATE INDEX follows_followee ON follows (followee_id) ;
+
+-- a reply points at the chirp it answers and at the first chirp of its
+-- thread, and is seen no more widely than the chirp it answers. Like quote_of
+-- they have no foreign key, so that replies outlive the chirps they answer.
+ALTER TABLE chirps
+ADD COLUMN reply_to UUID,
+ADD COLUMN thread_id UUID,
+ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0 ;
+
+CREATE INDEX chirps_reply_to ON chirps (reply_to, created_at, id)
+WHERE reply_to IS NOT NULL ;
+
+ALTER TABLE drafts
+ADD COLUMN reply_to UUID ;
+
+-- +goose Down
+ALTER TABLE drafts
+DROP COLUMN reply_to ;
+
+DROP INDEX chirps_reply_to ;
+
+ALTER TABLE chirps
+DROP COLUMN reply_count,
+DROP COLUMN thread_id,
+DROP COLUMN reply_to ;
+
+DROP TABLE follows ;
+
+ALTER TABLE drafts
+DROP COLUMN visibility ;
+
+ALTER TABLE chirps
+DROP COLUMN visibility ;
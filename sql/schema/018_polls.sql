@@ -0,0 +1,31 @@
+-- +goose Up
+-- a chirp has at most one poll, whose options are numbered from 1 in the
+-- order they were given. Each user votes once per poll.
+CREATE TABLE polls (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    closes_at TIMESTAMP NOT NULL
+) ;
+
+CREATE TABLE poll_options (
+    chirp_id UUID NOT NULL REFERENCES polls ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, position)
+) ;
+
+CREATE TABLE poll_votes (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id, position) REFERENCES poll_options ON DELETE CASCADE
+) ;
+
+-- +goose Down
+DROP TABLE poll_votes ;
+
+DROP TABLE poll_options ;
+
+DROP TABLE polls ;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- bookmarks are private to the user who saved them, and can be filed in
+-- named collections. Removing a collection keeps its bookmarks unfiled.
+CREATE TABLE bookmark_collections (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+) ;
+
+CREATE TABLE bookmarks (
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps ON DELETE CASCADE,
+    collection_id UUID REFERENCES bookmark_collections ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id)
+) ;
+
+CREATE INDEX bookmarks_user_created ON bookmarks (user_id, created_at DESC, chirp_id DESC) ;
+
+-- +goose Down
+DROP TABLE bookmarks ;
+
+DROP TABLE bookmark_collections ;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- chirps their authors pinned to the top of their profile
+CREATE TABLE chirp_pins (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+) ;
+
+CREATE INDEX chirp_pins_user ON chirp_pins (user_id, created_at DESC) ;
+
+-- +goose Down
+DROP TABLE chirp_pins ;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- handles are unique ignoring case, and existing users get a generated one
+-- they can change later. Avatars are uploads owned by the user.
+ALTER TABLE users
+ADD COLUMN handle TEXT,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_media_id UUID REFERENCES media_attachments ON DELETE SET NULL ;
+
+UPDATE users
+SET handle = 'user_' || substr(md5(id::TEXT), 1, 10) ;
+
+ALTER TABLE users
+ALTER COLUMN handle SET NOT NULL ;
+
+CREATE UNIQUE INDEX users_handle ON users (LOWER(handle)) ;
+
+-- +goose Down
+DROP INDEX users_handle ;
+
+ALTER TABLE users
+DROP COLUMN avatar_media_id,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle ;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- notifications with a group key collect their actors while unread, so many
+-- likes of one chirp show as one notification. Ungrouped notifications have
+-- no group key.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirps ON DELETE CASCADE,
+    group_key TEXT,
+    actor_ids UUID[] NOT NULL DEFAULT '{}',
+    actor_count INTEGER NOT NULL DEFAULT 0,
+    read_at TIMESTAMP
+) ;
+
+CREATE UNIQUE INDEX notifications_unread_group ON notifications (user_id, type, group_key)
+WHERE read_at IS NULL ;
+
+CREATE INDEX notifications_user_updated ON notifications (user_id, updated_at DESC, id DESC) ;
+
+-- types of notification the user turned off or back on
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type)
+) ;
+
+-- +goose Down
+DROP TABLE notification_preferences ;
+
+DROP TABLE notifications ;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- private conversations between two or a few users. One-to-one
+-- conversations have a direct key made of both user IDs, so each pair has at
+-- most one. Messages are kept apart from chirps and outlive their sender's
+-- account, so only resetting conversations removes them.
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users ON DELETE SET NULL,
+    is_group BOOLEAN NOT NULL,
+    direct_key TEXT UNIQUE
+) ;
+
+CREATE TABLE conversation_participants (
+    conversation_id UUID NOT NULL REFERENCES conversations ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL,
+    left_at TIMESTAMP,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    last_read_message_id UUID,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+) ;
+
+CREATE INDEX conversation_participants_user ON conversation_participants (user_id) ;
+
+CREATE TABLE messages (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    conversation_id UUID NOT NULL REFERENCES conversations ON DELETE CASCADE,
+    sender_id UUID REFERENCES users ON DELETE SET NULL,
+    body TEXT NOT NULL
+) ;
+
+CREATE INDEX messages_conversation_created ON messages (conversation_id, created_at DESC, id DESC) ;
+
+-- +goose Down
+DROP TABLE messages ;
+
+DROP TABLE conversation_participants ;
+
+DROP TABLE conversations ;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- users get no digest by email until they opt in to daily or weekly ones.
+-- The first one goes out a period after opting in.
+ALTER TABLE users
+ADD COLUMN digest_frequency TEXT NOT NULL DEFAULT 'off',
+ADD COLUMN digest_sent_at TIMESTAMP NOT NULL DEFAULT NOW() ;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN digest_sent_at,
+DROP COLUMN digest_frequency ;
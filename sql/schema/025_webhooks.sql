@@ -0,0 +1,57 @@
+-- +goose Up
+-- webhooks post events to a URL, signed with their secret. A user's webhook
+-- gets events about their own account, and an app webhook, registered by an
+-- admin, gets every public chirp.
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    scope TEXT NOT NULL DEFAULT 'user',
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+) ;
+
+CREATE INDEX webhooks_user ON webhooks (user_id) ;
+CREATE INDEX webhooks_app ON webhooks (scope) WHERE scope = 'app' ;
+
+-- each event sent to a webhook is a delivery, which stays pending and is
+-- retried with backoff until it is delivered, or is dead after too many
+-- failed attempts. An event that is no longer visible by the time it is sent
+-- is skipped.
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    webhook_id UUID NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    event_data JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL
+) ;
+
+CREATE INDEX webhook_deliveries_webhook_created ON webhook_deliveries (webhook_id, created_at DESC, id DESC) ;
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending' ;
+
+CREATE TABLE webhook_attempts (
+    id UUID PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries ON DELETE CASCADE,
+    attempted_at TIMESTAMP NOT NULL,
+    -- no status code when the receiver could not be reached
+    status_code INTEGER,
+    error TEXT,
+    duration_ms INTEGER NOT NULL
+) ;
+
+CREATE INDEX webhook_attempts_delivery ON webhook_attempts (delivery_id, attempted_at) ;
+
+-- +goose Down
+DROP TABLE webhook_attempts ;
+
+DROP TABLE webhook_deliveries ;
+
+DROP TABLE webhooks ;
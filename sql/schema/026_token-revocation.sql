@@ -0,0 +1,9 @@
+-- +goose Up
+-- access tokens issued before a user's password or email last changed are
+-- refused. Kept to the second, as tokens record when they were issued.
+ALTER TABLE users
+ADD COLUMN tokens_valid_after TIMESTAMP ;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN tokens_valid_after ;
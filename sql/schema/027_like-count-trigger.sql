@@ -0,0 +1,31 @@
+-- +goose Up
+-- like counts follow chirp_likes itself, so likes removed by cascade, like
+-- those of a deleted user, are no longer counted
+-- +goose StatementBegin
+CREATE FUNCTION count_chirp_like() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET like_count = like_count + 1 WHERE id = NEW.chirp_id ;
+    ELSE
+        UPDATE chirps SET like_count = like_count - 1 WHERE id = OLD.chirp_id ;
+    END IF ;
+    RETURN NULL ;
+END ;
+$$ LANGUAGE plpgsql ;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirp_likes_count
+AFTER INSERT OR DELETE ON chirp_likes
+FOR EACH ROW EXECUTE FUNCTION count_chirp_like() ;
+
+-- counts that already drifted are recounted
+UPDATE chirps
+SET like_count = (
+    SELECT COUNT(*) FROM chirp_likes
+    WHERE chirp_likes.chirp_id = chirps.id
+) ;
+
+-- +goose Down
+DROP TRIGGER chirp_likes_count ON chirp_likes ;
+
+DROP FUNCTION count_chirp_like() ;
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/trends"
+)
+
+const (
+    trendInterval  = time.Minute
+    trendMinCount  = 3
+    trendLimit     = 10
+)
+
+// recent window compared against the baseline period before it
+type trendWindow struct {
+    name      string
+    recent    time.Duration
+    baseline  time.Duration
+}
+
+var trendWindows = []trendWindow{
+    {name: "hour", recent: time.Hour, baseline: 24 * time.Hour},
+    {name: "day", recent: 24 * time.Hour, baseline: 7 * 24 * time.Hour},
+}
+
+type TrendResult struct {
+    Window      string          `json:"window"`
+    ComputedAt  time.Time       `json:"computed_at"`
+    Trends      []trends.Trend  `json:"trends"`
+}
+
+// latest ranking for each window, so requests never scan the buckets
+type trendCache struct {
+    mu       sync.RWMutex
+    results  map[string]TrendResult
+}
+
+func (c *trendCache) get(window string) (TrendResult, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    result, ok := c.results[window]
+    return result, ok
+}
+
+func (c *trendCache) set(result TrendResult) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.results == nil {
+        c.results = map[string]TrendResult{}
+    }
+    c.results[result.Window] = result
+}
+
+func (cfg *apiConfig) runTrendAggregator(ctx context.Context, interval time.Duration) {
+    // compute once at startup so the cache is warm, then on every tick
+    cfg.updateTrends(ctx)
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cfg.updateTrends(ctx)
+        }
+    }
+}
+
+func (cfg *apiConfig) updateTrends(ctx context.Context) {
+    // fold new hashtag uses into the buckets and drop the expired ones
+    if err := cfg.dbQueries.AggregateHashtagBuckets(ctx); err != nil {
+        log.Printf("error aggregating hashtag buckets: %s", err)
+        return
+    }
+    if err := cfg.dbQueries.PruneHashtagBuckets(ctx); err != nil {
+        log.Printf("error pruning hashtag buckets: %s", err)
+    }
+
+    // rank each window against its baseline
+    for _, window := range trendWindows {
+        rows, err := cfg.dbQueries.GetHashtagWindowCounts(ctx, database.GetHashtagWindowCountsParams{
+            RecentSeconds: int32(window.recent.Seconds()),
+            TotalSeconds:  int32((window.recent + window.baseline).Seconds()),
+        })
+        if err != nil {
+            log.Printf("error counting hashtags for %s trends: %s", window.name, err)
+            continue
+        }
+        counts := make([]trends.Count, 0, len(rows))
+        for _, row := range rows {
+            counts = append(counts, trends.Count{Tag: row.Tag, Recent: row.Recent, Baseline: row.Baseline})
+        }
+        periods := float64(window.baseline) / float64(window.recent)
+        cfg.trends.set(TrendResult{
+            Window:     window.name,
+            ComputedAt: time.Now().UTC(),
+            Trends:     trends.Rank(counts, periods, trendMinCount, trendLimit),
+        })
+    }
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/google/uuid"
+)
+
+// who can see a chirp. Unlisted chirps are left out of the global and
+// hashtag lists but are otherwise public.
+const (
+    visibilityPublic     = "public"
+    visibilityUnlisted   = "unlisted"
+    visibilityFollowers  = "followers"
+    visibilityDirect     = "direct"
+)
+
+func validVisibility(visibility string) bool {
+    switch visibility {
+    case visibilityPublic, visibilityUnlisted, visibilityFollowers, visibilityDirect:
+        return true
+    }
+    return false
+}
+
+// how widely each visibility shows a chirp
+var visibilityReach = map[string]int{
+    visibilityPublic:    3,
+    visibilityUnlisted:  2,
+    visibilityFollowers: 1,
+    visibilityDirect:    0,
+}
+
+// followers and direct chirps are only seen by some viewers
+func restrictedVisibility(visibility string) bool {
+    return visibility == visibilityFollowers || visibility == visibilityDirect
+}
+
+// leave out the followers and direct chirps the viewer cannot see, with one
+// query for the whole batch
+func (cfg *apiConfig) filterViewable(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) ([]database.Chirp, error) {
+    restricted := []uuid.UUID{}
+    for _, chirp := range chirps {
+        if restrictedVisibility(chirp.Visibility) && (!viewer.Valid || chirp.UserID != viewer.UUID) {
+            restricted = append(restricted, chirp.ID)
+        }
+    }
+    if len(restricted) == 0 {
+        return chirps, nil
+    }
+
+    viewable := map[uuid.UUID]struct{}{}
+    if viewer.Valid {
+        ids, err := cfg.dbQueries.GetViewableChirpIDs(ctx, database.GetViewableChirpIDsParams{
+            Ids:      restricted,
+            ViewerID: viewer.UUID,
+        })
+        if err != nil {
+            return nil, err
+        }
+        for _, id := range ids {
+            viewable[id] = struct{}{}
+        }
+    }
+    shown := make([]database.Chirp, 0, len(chirps))
+    for _, chirp := range chirps {
+        if !restrictedVisibility(chirp.Visibility) || (viewer.Valid && chirp.UserID == viewer.UUID) {
+            shown = append(shown, chirp)
+        } else if _, ok := viewable[chirp.ID]; ok {
+            shown = append(shown, chirp)
+        }
+    }
+    return shown, nil
+}
+
+// whether the viewer can see and interact with a single chirp. Authors always
+// can, while others need it to have passed moderation, to not be blocked by
+// or blocking the author, and to be allowed by its visibility.
+func (cfg *apiConfig) canViewChirp(ctx context.Context, viewer uuid.NullUUID, chirp database.Chirp) (bool, error) {
+    if viewer.Valid && chirp.UserID == viewer.UUID {
+        return true, nil
+    }
+    if chirp.ModerationState != chirpStateVisible {
+        return false, nil
+    }
+    if viewer.Valid {
+        blocked, err := cfg.isBlocked(ctx, viewer.UUID, chirp.UserID)
+        if err != nil || blocked {
+            return false, err
+        }
+    }
+    shown, err := cfg.filterViewable(ctx, viewer, []database.Chirp{chirp})
+    return len(shown) == 1, err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CraigYanitski/server-test/internal/database"
+	"github.com/CraigYanitski/server-test/internal/events"
+	"github.com/CraigYanitski/server-test/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+    webhookInterval   = 5 * time.Second
+    webhookBatch      = 50
+    webhookTimeout    = 10 * time.Second
+    // finished deliveries are kept this long for inspection
+    webhookRetention  = 30 * 24 * time.Hour
+)
+
+// who a webhook gets events about
+const (
+    // the user who registered it
+    webhookScopeUser  = "user"
+    // everyone, for public events only, registered by an admin
+    webhookScopeApp   = "app"
+)
+
+// states of a delivery
+const (
+    deliveryPending    = "pending"
+    deliveryDelivered  = "delivered"
+    deliverySkipped    = "skipped"
+    deliveryDead       = "dead"
+)
+
+// the events each scope of webhook can subscribe to
+var webhookEventTypes = map[string][]string{
+    webhookScopeUser: {eventChirpCreated, eventChirpDeleted, eventNotification, eventMessageCreated},
+    webhookScopeApp:  {eventChirpCreated, eventChirpDeleted},
+}
+
+// body of a webhook request
+type WebhookEvent struct {
+    ID         string     `json:"id"`
+    Type       string     `json:"type"`
+    CreatedAt  time.Time  `json:"created_at"`
+    Data       any        `json:"data"`
+}
+
+// queue an event for the webhooks that want it. User webhooks get events
+// about their user, and app webhooks get public chirps.
+func enqueueWebhooks(ctx context.Context, q *database.Queries, event events.Event, data eventData) error {
+    userIDs := []uuid.UUID{data.UserID}
+    public := false
+    switch event.Type {
+    case eventChirpCreated, eventChirpDeleted:
+        public = data.Visibility == visibilityPublic && data.ModerationState == chirpStateVisible
+    case eventMessageCreated:
+        userIDs = data.RecipientIDs
+    }
+    return q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+        EventID:   event.ID,
+        EventType: event.Type,
+        EventData: string(event.Data),
+        UserIds:   userIDs,
+        Public:    public,
+    })
+}
+
+func (cfg *apiConfig) runWebhooks(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cfg.deliverDueWebhooks(ctx)
+        }
+    }
+}
+
+// attempt the deliveries that are due, a batch at a time with the batch sent
+// in parallel
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context) {
+    for {
+        deliveries, err := cfg.dbQueries.ClaimDueWebhookDeliveries(ctx, webhookBatch)
+        if err != nil {
+            log.Printf("error claiming webhook deliveries: %s", err)
+            return
+        }
+        wg := sync.WaitGroup{}
+        for _, delivery := range deliveries {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                if err := cfg.deliverWebhook(ctx, delivery); err != nil {
+                    log.Printf("error delivering %s to webhook %s: %s", delivery.EventID, delivery.WebhookID, err)
+                }
+            }()
+        }
+        wg.Wait()
+        if len(deliveries) < webhookBatch || ctx.Err() != nil {
+            return
+        }
+    }
+}
+
+// make one attempt at a delivery and record how it went. Errors returned are
+// our own, and leave the delivery to be picked up again when its lease runs
+// out.
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, delivery database.WebhookDelivery) error {
+    hook, err := cfg.dbQueries.GetWebhook(ctx, delivery.WebhookID)
+    if err != nil {
+        return err
+    }
+    payload, err := cfg.webhookPayload(ctx, hook, delivery)
+    if err != nil {
+        return err
+    }
+    // the webhook was turned off, or the event is no longer visible to it
+    if !hook.Active || payload == nil {
+        return cfg.dbQueries.SkipWebhookDelivery(ctx, delivery.ID)
+    }
+    body, err := json.Marshal(WebhookEvent{
+        ID:        delivery.EventID,
+        Type:      delivery.EventType,
+        CreatedAt: delivery.CreatedAt,
+        Data:      payload,
+    })
+    if err != nil {
+        return err
+    }
+
+    start := time.Now()
+    status, err := cfg.webhooks.Deliver(ctx, webhook.Request{
+        WebhookID: hook.ID.String(),
+        EventID:   delivery.EventID,
+        EventType: delivery.EventType,
+        URL:       hook.Url,
+        Secret:    hook.Secret,
+        Body:      body,
+    })
+    if err != nil && ctx.Err() != nil {
+        // shutting down, so try again later rather than count it
+        return nil
+    }
+    attempt := database.RecordWebhookAttemptParams{
+        ID:            delivery.ID,
+        AttemptedAt:   start,
+        StatusCode:    sql.NullInt32{Int32: int32(status), Valid: status != 0},
+        DurationMs:    int32(time.Since(start).Milliseconds()),
+        Status:        deliveryDelivered,
+        NextAttemptAt: start,
+    }
+    if err != nil {
+        attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+        failures := int(delivery.Attempts) + 1
+        if failures >= webhook.MaxAttempts {
+            attempt.Status = deliveryDead
+        } else {
+            attempt.Status = deliveryPending
+            attempt.NextAttemptAt = start.Add(webhook.Backoff(failures))
+        }
+    }
+    // record the attempt even if we are shutting down
+    return cfg.dbQueries.RecordWebhookAttempt(context.WithoutCancel(ctx), attempt)
+}
+
+// what a webhook is sent for an event, looked up when it is sent so it is
+// only sent what its owner could see then, or nil if there is nothing
+func (cfg *apiConfig) webhookPayload(ctx context.Context, hook database.Webhook, delivery database.WebhookDelivery) (any, error) {
+    data := eventData{}
+    if err := json.Unmarshal(delivery.EventData, &data); err != nil {
+        return nil, err
+    }
+    if hook.Scope == webhookScopeApp {
+        switch delivery.EventType {
+        case eventChirpCreated:
+            return cfg.chirpPayload(ctx, uuid.NullUUID{}, data.ChirpID)
+        case eventChirpDeleted:
+            return StreamDeletion{ID: data.ChirpID}, nil
+        }
+        return nil, nil
+    }
+    return cfg.streamPayload(ctx, hook.UserID, delivery.EventType, data)
+}
+
+// delete deliveries that finished before the retention period
+func (cfg *apiConfig) purgeWebhookDeliveries(ctx context.Context) {
+    purged, err := cfg.dbQueries.PurgeWebhookDeliveries(ctx, int32(webhookRetention.Seconds()))
+    if err != nil {
+        log.Printf("error purging webhook deliveries: %s", err)
+        return
+    }
+    if purged > 0 {
+        log.Printf("purged %d webhook deliveries", purged)
+    }
+}